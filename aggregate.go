@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+const (
+	defaultWaitTimeout     = 30 * time.Minute
+	defaultPollInterval    = 15 * time.Second
+	defaultMaxPollInterval = 2 * time.Minute
+)
+
+// statePriority orders the states when combining them, the state with the highest priority wins.
+var statePriority = map[string]int{
+	"success":  0,
+	"skipped":  0,
+	"canceled": 1,
+	"pending":  2,
+	"created":  2,
+	"running":  3,
+	"failed":   4,
+}
+
+// childMatcher returns a function which selects the child statuses by context prefix or regex.
+func childMatcher(prefix, pattern, summary string) (func(string) bool, error) {
+	var re *regexp.Regexp
+	if pattern != "" {
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid context regex (%s): %s", pattern, err)
+		}
+	}
+
+	return func(name string) bool {
+		if name == summary {
+			return false
+		}
+		if re != nil {
+			return re.MatchString(name)
+		}
+		return strings.HasPrefix(name, prefix)
+	}, nil
+}
+
+// selectChildren returns the latest status of every child context, ordered by context name.
+func selectChildren(statuses []commitStatus, match func(string) bool) []commitStatus {
+	var children []commitStatus
+	for _, s := range latestStatuses(statuses) {
+		if match(s.Name) {
+			children = append(children, s)
+		}
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].Name < children[j].Name })
+	return children
+}
+
+// combineStates computes the summary state of the children:
+// any failed → failed, any running → running, any pending → pending, any canceled → canceled, all success → success.
+func combineStates(children []commitStatus) string {
+	combined := "success"
+	for _, c := range children {
+		if statePriority[c.Status] > statePriority[combined] {
+			combined = c.Status
+		}
+	}
+	if combined == "created" {
+		return "pending"
+	}
+	return combined
+}
+
+// summarizeStates returns a short description of the children's states, like "2 success, 1 running".
+func summarizeStates(children []commitStatus) string {
+	counts := map[string]int{}
+	var states []string
+	for _, c := range children {
+		if counts[c.Status] == 0 {
+			states = append(states, c.Status)
+		}
+		counts[c.Status]++
+	}
+	sort.Slice(states, func(i, j int) bool { return statePriority[states[i]] > statePriority[states[j]] })
+
+	var parts []string
+	for _, state := range states {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[state], state))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func fetchChildren(cfg config, match func(string) bool) ([]commitStatus, error) {
+	statuses, err := listStatuses(cfg, cfg.CommitHash, strings.TrimSpace(cfg.GitRef), "")
+	if err != nil {
+		return nil, err
+	}
+	return selectChildren(statuses, match), nil
+}
+
+// aggregateStatuses reports the combined state of the child contexts to the summary context,
+// optionally waiting for the children to finish.
+func aggregateStatuses(cfg config) error {
+	if cfg.AggregatePrefix == "" && cfg.AggregateRegex == "" {
+		return fmt.Errorf("aggregate mode requires a context prefix or regex")
+	}
+	match, err := childMatcher(cfg.AggregatePrefix, cfg.AggregateRegex, getContext(cfg.Context))
+	if err != nil {
+		return err
+	}
+
+	children, err := fetchChildren(cfg, match)
+	if err != nil {
+		return fmt.Errorf("failed to list statuses: %s", err)
+	}
+
+	if cfg.AggregateWait {
+		timeout, err := parseDuration(cfg.WaitTimeout, defaultWaitTimeout)
+		if err != nil {
+			return err
+		}
+		interval, err := parseDuration(cfg.PollInterval, defaultPollInterval)
+		if err != nil {
+			return err
+		}
+
+		if err := poll(timeout, interval, defaultMaxPollInterval, func() (bool, error) {
+			var err error
+			if children, err = fetchChildren(cfg, match); err != nil {
+				return false, err
+			}
+			state := combineStates(children)
+			log.Printf("%d child status(es): %s", len(children), summarizeStates(children))
+			return len(children) > 0 && !isUnfinished(state), nil
+		}); err == errPollTimeout {
+			log.Warnf("Child statuses did not finish in %s, reporting their current state", timeout)
+		} else if err != nil {
+			return fmt.Errorf("failed to list statuses: %s", err)
+		}
+	}
+
+	if len(children) == 0 {
+		return fmt.Errorf("no child statuses found for context prefix (%s) or regex (%s)", cfg.AggregatePrefix, cfg.AggregateRegex)
+	}
+
+	log.Infof("Child statuses:")
+	for _, c := range children {
+		log.Printf("- %s: %s", c.Name, c.Status)
+	}
+
+	cfg.Status = combineStates(children)
+	if cfg.Description == "" {
+		cfg.Description = summarizeStates(children)
+	}
+	if _, err := sendStatusWithRetry(cfg); err != nil {
+		return err
+	}
+	log.Donef("Reported %s status to %s", cfg.Status, getContext(cfg.Context))
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import "testing"
+
+func Test_combineStates(t *testing.T) {
+	tests := []struct {
+		name   string
+		states []string
+		want   string
+	}{
+		{"all success", []string{"success", "success"}, "success"},
+		{"any failed", []string{"success", "running", "failed"}, "failed"},
+		{"any running", []string{"success", "running", "pending"}, "running"},
+		{"pending", []string{"success", "pending"}, "pending"},
+		{"created", []string{"created"}, "pending"},
+		{"canceled", []string{"success", "canceled"}, "canceled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var children []commitStatus
+			for _, state := range tt.states {
+				children = append(children, commitStatus{Status: state})
+			}
+			if got := combineStates(children); got != tt.want {
+				t.Errorf("combineStates() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_childMatcher(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefix  string
+		pattern string
+		context string
+		want    bool
+	}{
+		{"prefix match", "bitrise/", "", "bitrise/unit", true},
+		{"prefix no match", "bitrise/", "", "gitlab-ci", false},
+		{"summary excluded", "bitrise", "", "bitrise", false},
+		{"regex match", "", `^bitrise/(unit|ui)$`, "bitrise/ui", true},
+		{"regex no match", "", `^bitrise/(unit|ui)$`, "bitrise/lint", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, err := childMatcher(tt.prefix, tt.pattern, "bitrise")
+			if err != nil {
+				t.Fatalf("childMatcher() error = %v", err)
+			}
+			if got := match(tt.context); got != tt.want {
+				t.Errorf("match(%s) = %v, want %v", tt.context, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_selectChildren(t *testing.T) {
+	// Retried contexts keep their earlier statuses as history, listed the oldest first.
+	statuses := []commitStatus{
+		{ID: 1, Name: "bitrise/unit", Ref: "main", Status: "failed"},
+		{ID: 2, Name: "bitrise/ui", Ref: "main", Status: "running"},
+		{ID: 3, Name: "gitlab-ci", Ref: "main", Status: "failed"},
+		{ID: 4, Name: "bitrise/unit", Ref: "main", Status: "success"},
+		{ID: 5, Name: "bitrise/ui", Ref: "main", Status: "success"},
+	}
+	match, err := childMatcher("bitrise/", "", "bitrise")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	children := selectChildren(statuses, match)
+	if len(children) != 2 || children[0].ID != 5 || children[1].ID != 4 {
+		t.Errorf("selectChildren() = %v, want the latest statuses of bitrise/ui and bitrise/unit", children)
+	}
+	if got := combineStates(children); got != "success" {
+		t.Errorf("combineStates() = %v, want success", got)
+	}
+}
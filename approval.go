@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// approvals are the approvals of a merge request as returned by the GitLab API.
+// see also: https://docs.gitlab.com/ee/api/merge_request_approvals.html
+type approvals struct {
+	ApprovedBy []struct {
+		User user `json:"user"`
+	} `json:"approved_by"`
+}
+
+// approvedBy returns whether the user approved the merge request.
+func (a approvals) approvedBy(userID int) bool {
+	for _, approver := range a.ApprovedBy {
+		if approver.User.ID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// currentUser returns the user of the private token.
+func currentUser(cfg config) (user, error) {
+	var u user
+	err := apiRequest(cfg, http.MethodGet, "user", nil, nil, "", &u)
+	return u, err
+}
+
+func getApprovals(cfg config, iid int) (approvals, error) {
+	var a approvals
+	path := fmt.Sprintf("projects/%s/merge_requests/%d/approvals", projectPath(cfg), iid)
+	err := apiRequest(cfg, http.MethodGet, path, nil, nil, "", &a)
+	return a, err
+}
+
+// updateApproval approves the merge request's head commit when the build succeeds,
+// and revokes the approval of the token's user when the build fails or is canceled.
+// The approval is bound to the head commit, so a newer push invalidates it.
+func updateApproval(cfg config, mr *mergeRequest, state string) error {
+	if isUnfinished(state) {
+		return nil
+	}
+
+	me, err := currentUser(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to get the current user: %s", err)
+	}
+	a, err := getApprovals(cfg, mr.IID)
+	if err != nil {
+		return fmt.Errorf("failed to get approvals: %s", err)
+	}
+	approved := a.approvedBy(me.ID)
+
+	switch {
+	case state == "success" && !approved:
+		form := url.Values{"sha": {mr.SHA}}
+		path := fmt.Sprintf("projects/%s/merge_requests/%d/approve", projectPath(cfg), mr.IID)
+		if err := apiRequest(cfg, http.MethodPost, path, nil, strings.NewReader(form.Encode()), "application/x-www-form-urlencoded", nil); err != nil {
+			return fmt.Errorf("failed to approve merge request !%d: %s", mr.IID, err)
+		}
+		log.Donef("Approved merge request !%d at %s as %s", mr.IID, shortSHA(mr.SHA), me.Username)
+	case state != "success" && approved:
+		path := fmt.Sprintf("projects/%s/merge_requests/%d/unapprove", projectPath(cfg), mr.IID)
+		if err := apiRequest(cfg, http.MethodPost, path, nil, nil, "", nil); err != nil {
+			return fmt.Errorf("failed to revoke approval of merge request !%d: %s", mr.IID, err)
+		}
+		log.Donef("Revoked the approval of merge request !%d by %s", mr.IID, me.Username)
+	}
+	return nil
+}
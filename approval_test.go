@@ -0,0 +1,21 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func Test_approvals_approvedBy(t *testing.T) {
+	var a approvals
+	data := `{"approved_by": [{"user": {"id": 3, "username": "reviewer"}}, {"user": {"id": 7, "username": "bitrise-bot"}}]}`
+	if err := json.Unmarshal([]byte(data), &a); err != nil {
+		t.Fatal(err)
+	}
+
+	if !a.approvedBy(7) {
+		t.Errorf("approvedBy(7) = false, want true")
+	}
+	if a.approvedBy(5) {
+		t.Errorf("approvedBy(5) = true, want false")
+	}
+}
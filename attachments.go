@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+const (
+	defaultAttachmentMaxSizeMB = 10
+	maxAttachments             = 10
+)
+
+// upload is an uploaded project file as returned by the GitLab API.
+// see also: https://docs.gitlab.com/ee/api/projects.html#upload-a-file
+type upload struct {
+	Alt      string `json:"alt"`
+	URL      string `json:"url"`
+	FullPath string `json:"full_path"`
+	Markdown string `json:"markdown"`
+}
+
+// uploadFile uploads the file to the project, the returned markdown can be embedded in comments.
+func uploadFile(cfg config, pth string) (upload, error) {
+	f, err := os.Open(pth)
+	if err != nil {
+		return upload{}, err
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Warnf("Failed to close file (%s), error: %s", pth, err)
+		}
+	}()
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", filepath.Base(pth))
+	if err != nil {
+		return upload{}, err
+	}
+	if _, err := io.Copy(part, f); err != nil {
+		return upload{}, err
+	}
+	if err := w.Close(); err != nil {
+		return upload{}, err
+	}
+
+	var u upload
+	path := fmt.Sprintf("projects/%s/uploads", projectPath(cfg))
+	err = apiRequest(cfg, http.MethodPost, path, nil, &body, w.FormDataContentType(), &u)
+	return u, err
+}
+
+// createCommitComment adds a comment to the commit.
+// see also: https://docs.gitlab.com/ee/api/commits.html#post-comment-to-commit
+func createCommitComment(cfg config, sha, body string) error {
+	form := url.Values{"note": {body}}
+	path := fmt.Sprintf("projects/%s/repository/commits/%s/comments", projectPath(cfg), sha)
+	return apiRequest(cfg, http.MethodPost, path, nil, strings.NewReader(form.Encode()), "application/x-www-form-urlencoded", nil)
+}
+
+// findAttachments returns the files matching the patterns which are not larger than maxSize bytes,
+// at most maxAttachments files are returned.
+func findAttachments(patterns []string, maxSize int64) ([]string, error) {
+	var pths []string
+	seen := map[string]bool{}
+	for _, pattern := range patterns {
+		if pattern = strings.TrimSpace(pattern); pattern == "" {
+			continue
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid attachment path (%s): %s", pattern, err)
+		}
+
+		for _, pth := range matches {
+			info, err := os.Stat(pth)
+			if err != nil || info.IsDir() || seen[pth] {
+				continue
+			}
+			seen[pth] = true
+			if info.Size() > maxSize {
+				log.Warnf("Skipping %s, its size (%d bytes) exceeds the limit (%d bytes)", pth, info.Size(), maxSize)
+				continue
+			}
+			if len(pths) == maxAttachments {
+				log.Warnf("Reached the limit of %d attachments, skipping the remaining files", maxAttachments)
+				return pths, nil
+			}
+			pths = append(pths, pth)
+		}
+	}
+	return pths, nil
+}
+
+// attachmentsSection uploads the attachments and renders their markdown, images are displayed inline by GitLab.
+func attachmentsSection(cfg config) (string, error) {
+	maxSizeMB := cfg.AttachmentMaxSizeMB
+	if maxSizeMB <= 0 {
+		maxSizeMB = defaultAttachmentMaxSizeMB
+	}
+	pths, err := findAttachments(cfg.AttachmentPaths, int64(maxSizeMB)*1024*1024)
+	if err != nil {
+		return "", err
+	}
+	if len(pths) == 0 {
+		return "", nil
+	}
+
+	var items []string
+	for _, pth := range pths {
+		u, err := uploadFile(cfg, pth)
+		if err != nil {
+			log.Warnf("Failed to upload %s, error: %s", pth, err)
+			continue
+		}
+		log.Printf("Uploaded %s", pth)
+		items = append(items, u.Markdown)
+	}
+	if len(items) == 0 {
+		return "", fmt.Errorf("failed to upload attachments")
+	}
+	return fmt.Sprintf("**Attachments**\n\n%s\n", strings.Join(items, "\n\n")), nil
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_findAttachments(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]int{"login.png": 10, "home.png": 100, "video.mp4": 10}
+	for name, size := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(strings.Repeat("x", size)), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := findAttachments([]string{filepath.Join(dir, "*.png"), filepath.Join(dir, "login.png"), ""}, 50)
+	if err != nil {
+		t.Fatalf("findAttachments() error = %v", err)
+	}
+	if len(got) != 1 || filepath.Base(got[0]) != "login.png" {
+		t.Errorf("findAttachments() = %v, want only login.png", got)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// mergeBlockReason explains why the merge request cannot be merged based on its detailed merge status,
+// it returns an empty string if the reason is unknown.
+// see also: https://docs.gitlab.com/ee/api/merge_requests.html#merge-status
+func mergeBlockReason(mr *mergeRequest) string {
+	switch mr.DetailedMergeStatus {
+	case "conflict":
+		return fmt.Sprintf("it has conflicts with %s, rebase it or resolve the conflicts", mr.TargetBranch)
+	case "need_rebase":
+		return fmt.Sprintf("it must be rebased on %s", mr.TargetBranch)
+	case "discussions_not_resolved":
+		return "it has unresolved discussions"
+	case "not_approved":
+		return "it is missing required approvals"
+	case "draft_status":
+		return "it is a draft"
+	case "not_open":
+		return "it is not open"
+	case "blocked_status":
+		return "it is blocked by another merge request"
+	}
+	if mr.HasConflicts {
+		return fmt.Sprintf("it has conflicts with %s, rebase it or resolve the conflicts", mr.TargetBranch)
+	}
+	if !mr.BlockingDiscussionsResolved {
+		return "it has unresolved discussions"
+	}
+	return ""
+}
+
+// isMergeBlocked returns whether the merge request cannot be merged until it is changed,
+// missing approvals do not block setting it to merge when the pipeline succeeds.
+func isMergeBlocked(mr *mergeRequest) bool {
+	switch mr.DetailedMergeStatus {
+	case "conflict", "draft_status", "not_open":
+		return true
+	}
+	return mr.HasConflicts
+}
+
+// mergeWhenPipelineSucceeds sets the opted-in merge request to merge when its pipeline succeeds.
+// The merge is bound to the built commit, so it is rejected if the merge request has been updated since.
+func mergeWhenPipelineSucceeds(cfg config, mr *mergeRequest) error {
+	if !containsString(mr.Labels, cfg.AutoMergeLabel) {
+		log.Printf("Merge request !%d is not labeled %s, not merging", mr.IID, cfg.AutoMergeLabel)
+		return nil
+	}
+	if mr.MergeWhenPipelineSucceeds {
+		log.Printf("Merge request !%d is already set to merge when the pipeline succeeds", mr.IID)
+		return nil
+	}
+	if isMergeBlocked(mr) {
+		return fmt.Errorf("cannot merge merge request !%d: %s", mr.IID, mergeBlockReason(mr))
+	}
+
+	form := url.Values{"merge_when_pipeline_succeeds": {"true"}, "sha": {mr.SHA}}
+	path := fmt.Sprintf("projects/%s/merge_requests/%d/merge", projectPath(cfg), mr.IID)
+	err := apiRequest(cfg, http.MethodPut, path, nil, strings.NewReader(form.Encode()), "application/x-www-form-urlencoded", nil)
+	if err == nil {
+		log.Donef("Merge request !%d is set to merge when the pipeline succeeds", mr.IID)
+		return nil
+	}
+
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict {
+		return fmt.Errorf("cannot merge merge request !%d: it has been updated since %s was built", mr.IID, shortSHA(mr.SHA))
+	}
+	// The merge request is fetched again, as its merge status might have changed since the build started.
+	if current, getErr := getMergeRequest(cfg, fmt.Sprintf("%d", mr.IID)); getErr == nil {
+		if reason := mergeBlockReason(current); reason != "" {
+			return fmt.Errorf("cannot merge merge request !%d: %s", mr.IID, reason)
+		}
+	}
+	return fmt.Errorf("failed to merge merge request !%d: %s", mr.IID, err)
+}
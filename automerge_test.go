@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+func Test_mergeBlockReason(t *testing.T) {
+	tests := []struct {
+		name        string
+		mr          mergeRequest
+		want        string
+		wantBlocked bool
+	}{
+		{
+			name: "mergeable",
+			mr:   mergeRequest{DetailedMergeStatus: "mergeable", BlockingDiscussionsResolved: true},
+		},
+		{
+			name:        "conflicts",
+			mr:          mergeRequest{DetailedMergeStatus: "conflict", TargetBranch: "main", HasConflicts: true},
+			want:        "it has conflicts with main, rebase it or resolve the conflicts",
+			wantBlocked: true,
+		},
+		{
+			name: "unresolved discussions",
+			mr:   mergeRequest{DetailedMergeStatus: "discussions_not_resolved"},
+			want: "it has unresolved discussions",
+		},
+		{
+			name: "missing approvals",
+			mr:   mergeRequest{DetailedMergeStatus: "not_approved", BlockingDiscussionsResolved: true},
+			want: "it is missing required approvals",
+		},
+		{
+			name:        "conflicts on older GitLab",
+			mr:          mergeRequest{TargetBranch: "develop", HasConflicts: true, BlockingDiscussionsResolved: true},
+			want:        "it has conflicts with develop, rebase it or resolve the conflicts",
+			wantBlocked: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeBlockReason(&tt.mr); got != tt.want {
+				t.Errorf("mergeBlockReason() = %v, want %v", got, tt.want)
+			}
+			if got := isMergeBlocked(&tt.mr); got != tt.wantBlocked {
+				t.Errorf("isMergeBlocked() = %v, want %v", got, tt.wantBlocked)
+			}
+		})
+	}
+}
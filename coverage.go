@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// lineCoverage holds whether the lines of the source files are covered, keyed by file path and line number.
+// Reports are merged by line: a line is covered if any report covers it.
+type lineCoverage map[string]map[int]bool
+
+func (c lineCoverage) add(file string, line int, hits int) {
+	if line <= 0 {
+		return
+	}
+	file = filepath.ToSlash(filepath.Clean(file))
+	if c[file] == nil {
+		c[file] = map[int]bool{}
+	}
+	c[file][line] = c[file][line] || hits > 0
+}
+
+// percent returns the ratio of covered lines in percent.
+func (c lineCoverage) percent() float64 {
+	var total, covered int
+	for _, lines := range c {
+		for _, isCovered := range lines {
+			total++
+			if isCovered {
+				covered++
+			}
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(covered) / float64(total) * 100
+}
+
+type coberturaReport struct {
+	Packages []struct {
+		Classes []struct {
+			Filename string `xml:"filename,attr"`
+			Lines    []struct {
+				Number int `xml:"number,attr"`
+				Hits   int `xml:"hits,attr"`
+			} `xml:"lines>line"`
+		} `xml:"classes>class"`
+	} `xml:"packages>package"`
+}
+
+type jacocoPackage struct {
+	Name        string `xml:"name,attr"`
+	SourceFiles []struct {
+		Name  string `xml:"name,attr"`
+		Lines []struct {
+			Number          int `xml:"nr,attr"`
+			CoveredInstrs   int `xml:"ci,attr"`
+			MissedInstrs    int `xml:"mi,attr"`
+			CoveredBranches int `xml:"cb,attr"`
+			MissedBranches  int `xml:"mb,attr"`
+		} `xml:"line"`
+	} `xml:"sourcefile"`
+}
+
+// jacocoGroup is the report or a group of it, multi-module reports group the packages of every module.
+type jacocoGroup struct {
+	Groups   []jacocoGroup   `xml:"group"`
+	Packages []jacocoPackage `xml:"package"`
+}
+
+// packages returns the packages of the group and its nested groups.
+func (g jacocoGroup) packages() []jacocoPackage {
+	packages := g.Packages
+	for _, group := range g.Groups {
+		packages = append(packages, group.packages()...)
+	}
+	return packages
+}
+
+type cloverFile struct {
+	Name  string `xml:"name,attr"`
+	Path  string `xml:"path,attr"`
+	Lines []struct {
+		Num   int    `xml:"num,attr"`
+		Type  string `xml:"type,attr"`
+		Count int    `xml:"count,attr"`
+	} `xml:"line"`
+}
+
+type cloverReport struct {
+	Project struct {
+		Files    []cloverFile `xml:"file"`
+		Packages []struct {
+			Files []cloverFile `xml:"file"`
+		} `xml:"package"`
+	} `xml:"project"`
+}
+
+func parseCobertura(data []byte, c lineCoverage) error {
+	var report coberturaReport
+	if err := xml.Unmarshal(data, &report); err != nil {
+		return err
+	}
+	for _, p := range report.Packages {
+		for _, class := range p.Classes {
+			for _, l := range class.Lines {
+				c.add(class.Filename, l.Number, l.Hits)
+			}
+		}
+	}
+	return nil
+}
+
+func parseJaCoCo(data []byte, c lineCoverage) error {
+	var report jacocoGroup
+	if err := xml.Unmarshal(data, &report); err != nil {
+		return err
+	}
+	for _, p := range report.packages() {
+		for _, f := range p.SourceFiles {
+			for _, l := range f.Lines {
+				if l.CoveredInstrs+l.MissedInstrs == 0 {
+					continue
+				}
+				c.add(path.Join(p.Name, f.Name), l.Number, l.CoveredInstrs)
+			}
+		}
+	}
+	return nil
+}
+
+func parseClover(data []byte, c lineCoverage) error {
+	var report cloverReport
+	if err := xml.Unmarshal(data, &report); err != nil {
+		return err
+	}
+	files := report.Project.Files
+	for _, p := range report.Project.Packages {
+		files = append(files, p.Files...)
+	}
+	for _, f := range files {
+		name := f.Path
+		if name == "" {
+			name = f.Name
+		}
+		for _, l := range f.Lines {
+			if l.Type == "method" {
+				continue
+			}
+			c.add(name, l.Num, l.Count)
+		}
+	}
+	return nil
+}
+
+func parseLCOV(data []byte, c lineCoverage) error {
+	var file string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "SF:"):
+			file = strings.TrimPrefix(line, "SF:")
+		case strings.HasPrefix(line, "DA:"):
+			fields := strings.Split(strings.TrimPrefix(line, "DA:"), ",")
+			if len(fields) < 2 {
+				return fmt.Errorf("invalid line: %s", line)
+			}
+			number, err := strconv.Atoi(fields[0])
+			if err != nil {
+				return fmt.Errorf("invalid line: %s", line)
+			}
+			hits, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return fmt.Errorf("invalid line: %s", line)
+			}
+			c.add(file, number, hits)
+		case line == "end_of_record":
+			file = ""
+		}
+	}
+	return scanner.Err()
+}
+
+// parseGoCoverprofile parses a Go coverage profile, the lines of every block are considered covered if the block is.
+func parseGoCoverprofile(data []byte, c lineCoverage) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "mode:") {
+			continue
+		}
+
+		// name.go:line.column,line.column numberOfStatements count
+		var file string
+		var startLine, startCol, endLine, endCol, statements, count int
+		idx := strings.LastIndex(line, ":")
+		if idx == -1 {
+			return fmt.Errorf("invalid line: %s", line)
+		}
+		file = line[:idx]
+		if _, err := fmt.Sscanf(line[idx+1:], "%d.%d,%d.%d %d %d", &startLine, &startCol, &endLine, &endCol, &statements, &count); err != nil {
+			return fmt.Errorf("invalid line: %s", line)
+		}
+		if statements == 0 {
+			continue
+		}
+		for l := startLine; l <= endLine; l++ {
+			c.add(file, l, count)
+		}
+	}
+	return scanner.Err()
+}
+
+// parseSimpleCov parses a SimpleCov .resultset.json, which holds the results of every test command.
+func parseSimpleCov(data []byte, c lineCoverage) error {
+	var resultset map[string]struct {
+		Coverage map[string]json.RawMessage `json:"coverage"`
+	}
+	if err := json.Unmarshal(data, &resultset); err != nil {
+		return err
+	}
+
+	for _, result := range resultset {
+		for file, raw := range result.Coverage {
+			// SimpleCov 0.18+ nests the line coverage under "lines", older versions store the array directly.
+			var lines []*int
+			var nested struct {
+				Lines []*int `json:"lines"`
+			}
+			if err := json.Unmarshal(raw, &nested); err == nil {
+				lines = nested.Lines
+			} else if err := json.Unmarshal(raw, &lines); err != nil {
+				return fmt.Errorf("invalid coverage of %s: %s", file, err)
+			}
+
+			for i, hits := range lines {
+				if hits != nil {
+					c.add(file, i+1, *hits)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// xmlRoot returns the name of the root element and its first child element.
+func xmlRoot(data []byte) (string, string) {
+	var names []string
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for len(names) < 2 {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			names = append(names, start.Name.Local)
+		}
+	}
+	for len(names) < 2 {
+		names = append(names, "")
+	}
+	return names[0], names[1]
+}
+
+// parseCoverageReport detects the format of the coverage report by its content and adds its lines to the coverage.
+func parseCoverageReport(pth string, c lineCoverage) error {
+	data, err := ioutil.ReadFile(pth)
+	if err != nil {
+		return err
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return parseSimpleCov(data, c)
+	case bytes.HasPrefix(trimmed, []byte("mode:")):
+		return parseGoCoverprofile(data, c)
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		root, child := xmlRoot(data)
+		switch {
+		case root == "report":
+			return parseJaCoCo(data, c)
+		case root == "coverage" && child == "project":
+			return parseClover(data, c)
+		case root == "coverage":
+			return parseCobertura(data, c)
+		}
+		return fmt.Errorf("unknown XML coverage format: %s", root)
+	case bytes.Contains(data, []byte("SF:")):
+		return parseLCOV(data, c)
+	}
+	return fmt.Errorf("unknown coverage format")
+}
+
+// computeCoverage merges the coverage reports matching the patterns by line and returns the coverage in percent.
+func computeCoverage(patterns []string) (float64, error) {
+	c := lineCoverage{}
+	var reports int
+	for _, pattern := range patterns {
+		if pattern = strings.TrimSpace(pattern); pattern == "" {
+			continue
+		}
+		pths, err := filepath.Glob(pattern)
+		if err != nil {
+			return 0, fmt.Errorf("invalid coverage report path (%s): %s", pattern, err)
+		}
+		for _, pth := range pths {
+			if err := parseCoverageReport(pth, c); err != nil {
+				return 0, fmt.Errorf("failed to parse coverage report (%s): %s", pth, err)
+			}
+			log.Printf("Parsed coverage report: %s", pth)
+			reports++
+		}
+	}
+	if reports == 0 {
+		return 0, fmt.Errorf("no coverage report found at %v", patterns)
+	}
+	if len(c) == 0 {
+		return 0, fmt.Errorf("no lines found in the coverage reports at %v", patterns)
+	}
+	return c.percent(), nil
+}
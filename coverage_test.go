@@ -0,0 +1,78 @@
+package main
+
+import (
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func Test_computeCoverage(t *testing.T) {
+	reports := map[string]string{
+		"lcov.info": "TN:\nSF:src/a.js\nDA:1,1\nDA:2,0\nDA:3,0\nend_of_record\n",
+		"cobertura.xml": `<?xml version="1.0" ?>
+<coverage line-rate="0.5"><packages><package name="src"><classes>
+<class name="a" filename="src/a.js"><lines><line number="2" hits="3"/><line number="4" hits="0"/></lines></class>
+</classes></package></packages></coverage>`,
+		"jacoco.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<!DOCTYPE report PUBLIC "-//JACOCO//DTD Report 1.1//EN" "report.dtd">
+<report name="app"><package name="com/example"><sourcefile name="Main.kt">
+<line nr="3" mi="0" ci="2" mb="0" cb="0"/><line nr="4" mi="3" ci="0" mb="0" cb="0"/>
+</sourcefile></package></report>`,
+		"jacoco-modules.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<report name="app"><group name="core"><package name="com/example/core"><sourcefile name="Core.kt">
+<line nr="3" mi="0" ci="2" mb="0" cb="0"/>
+</sourcefile></package></group><group name="features"><group name="login"><package name="com/example/login"><sourcefile name="Login.kt">
+<line nr="7" mi="4" ci="0" mb="0" cb="0"/>
+</sourcefile></package></group></group></report>`,
+		"empty.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<report name="app"><group name="core"></group></report>`,
+		"clover.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<coverage generated="1"><project timestamp="1"><package name="app"><file name="b.php" path="/src/b.php">
+<line num="1" type="method" count="1"/><line num="2" type="stmt" count="1"/><line num="3" type="stmt" count="0"/>
+</file></package></project></coverage>`,
+		"cover.out":       "mode: set\ngithub.com/example/c.go:3.10,5.2 2 1\ngithub.com/example/c.go:7.10,7.20 1 0\n",
+		".resultset.json": `{"RSpec": {"coverage": {"/src/d.rb": {"lines": [null, 1, 0, null]}}, "timestamp": 1}}`,
+	}
+
+	dir := t.TempDir()
+	for name, content := range reports {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		patterns []string
+		want     float64
+	}{
+		{"lcov", []string{filepath.Join(dir, "lcov.info")}, 100.0 / 3},
+		{"cobertura", []string{filepath.Join(dir, "cobertura.xml")}, 50},
+		{"lcov and cobertura merged by line", []string{filepath.Join(dir, "lcov.info"), filepath.Join(dir, "cobertura.xml")}, 50},
+		{"jacoco", []string{filepath.Join(dir, "jacoco.xml")}, 50},
+		{"jacoco multi-module", []string{filepath.Join(dir, "jacoco-modules.xml")}, 50},
+		{"clover", []string{filepath.Join(dir, "clover.xml")}, 50},
+		{"go coverprofile", []string{filepath.Join(dir, "cover.out")}, 75},
+		{"simplecov", []string{filepath.Join(dir, ".resultset.json")}, 50},
+		{"glob", []string{filepath.Join(dir, "*.xml")}, 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := computeCoverage(tt.patterns)
+			if err != nil {
+				t.Fatalf("computeCoverage() error = %v", err)
+			}
+			if math.Abs(got-tt.want) > 0.001 {
+				t.Errorf("computeCoverage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := computeCoverage([]string{filepath.Join(dir, "missing.xml")}); err == nil {
+		t.Errorf("computeCoverage() expected error for missing report")
+	}
+	if _, err := computeCoverage([]string{filepath.Join(dir, "empty.xml")}); err == nil {
+		t.Errorf("computeCoverage() expected error for report without lines")
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// baselineCommitLimit is the number of commits checked on the baseline branch for a status with coverage.
+const baselineCommitLimit = 10
+
+// baselineBranch returns the branch whose coverage the build is compared to:
+// the target branch of the merge request or the default branch of the project.
+func baselineBranch(cfg config, mr *mergeRequest) (string, error) {
+	if mr != nil && mr.TargetBranch != "" {
+		return mr.TargetBranch, nil
+	}
+	p, err := getProjectInfo(cfg)
+	if err != nil {
+		return "", err
+	}
+	return p.DefaultBranch, nil
+}
+
+// baselineStatus returns the latest successful status with coverage, the coverage of running
+// or failed builds is not a baseline.
+func baselineStatus(statuses []commitStatus) *commitStatus {
+	var latest *commitStatus
+	for i, s := range statuses {
+		if s.Status == "success" && s.Coverage != nil && (latest == nil || s.ID > latest.ID) {
+			latest = &statuses[i]
+		}
+	}
+	return latest
+}
+
+// fetchBaselineCoverage returns the coverage of the latest successful status in the configured context on the baseline branch.
+func fetchBaselineCoverage(cfg config, mr *mergeRequest) (float64, bool, error) {
+	branch, err := baselineBranch(cfg, mr)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get default branch: %s", err)
+	}
+
+	commits, err := listCommits(cfg, branch, baselineCommitLimit)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to list commits of %s: %s", branch, err)
+	}
+
+	context := getContext(cfg.Context)
+	for _, c := range commits {
+		if c.ID == cfg.CommitHash {
+			continue
+		}
+		statuses, err := listStatuses(cfg, c.ID, "", context)
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to list statuses of %s: %s", c.ShortID, err)
+		}
+
+		if latest := baselineStatus(statuses); latest != nil {
+			log.Printf("Baseline coverage of %s (%s): %.2f%%", branch, c.ShortID, *latest.Coverage)
+			return *latest.Coverage, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// formatCoverage formats the coverage with its change, for example "Coverage 81.2% (−0.6%)".
+func formatCoverage(coverage float64, delta *float64) string {
+	s := fmt.Sprintf("Coverage %.1f%%", coverage)
+	if delta == nil {
+		return s
+	}
+	switch d := math.Round(*delta*10) / 10; {
+	case d > 0:
+		return s + fmt.Sprintf(" (+%.1f%%)", d)
+	case d < 0:
+		return s + fmt.Sprintf(" (−%.1f%%)", -d)
+	}
+	return s + " (±0.0%)"
+}
+
+// coverageViolation checks the coverage against the minimum and the maximum allowed drop.
+func coverageViolation(coverage float64, delta, min, maxDrop *float64) error {
+	if min != nil && coverage < *min {
+		return fmt.Errorf("coverage %.2f%% is below the minimum %.2f%%", coverage, *min)
+	}
+	if maxDrop != nil && delta != nil && -*delta > *maxDrop {
+		return fmt.Errorf("coverage dropped by %.2f%%, more than the allowed %.2f%%", -*delta, *maxDrop)
+	}
+	return nil
+}
+
+func isCoverageCheckEnabled(cfg config) bool {
+	return cfg.CoverageCompare || cfg.CoverageMin != nil || cfg.CoverageMaxDrop != nil
+}
+
+// checkCoverage compares the coverage to the baseline and applies the gates: the delta is added to the description
+// and the status is reported as failed if a gate is violated. The violation is returned as an error.
+func checkCoverage(cfg config, mr *mergeRequest) (config, error) {
+	if cfg.Coverage == nil {
+		log.Warnf("No coverage set or computed, skipping the coverage gate")
+		return cfg, nil
+	}
+
+	var delta *float64
+	if cfg.CoverageCompare || cfg.CoverageMaxDrop != nil {
+		baseline, ok, err := fetchBaselineCoverage(cfg, mr)
+		if err != nil {
+			log.Warnf("Failed to fetch baseline coverage, error: %s", err)
+		} else if !ok {
+			log.Warnf("No baseline coverage found")
+		} else {
+			d := *cfg.Coverage - baseline
+			delta = &d
+		}
+	}
+
+	violation := coverageViolation(*cfg.Coverage, delta, cfg.CoverageMin, cfg.CoverageMaxDrop)
+	if violation != nil {
+		log.Warnf("Coverage gate failed: %s", violation)
+		cfg.Status = "failed"
+	}
+	cfg.Description = fmt.Sprintf("%s, %s", getDescription(cfg.Description, cfg.Status), formatCoverage(*cfg.Coverage, delta))
+	return cfg, violation
+}
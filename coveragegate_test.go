@@ -0,0 +1,81 @@
+package main
+
+import "testing"
+
+func Test_formatCoverage(t *testing.T) {
+	delta := func(d float64) *float64 { return &d }
+	tests := []struct {
+		name     string
+		coverage float64
+		delta    *float64
+		want     string
+	}{
+		{"no baseline", 81.23, nil, "Coverage 81.2%"},
+		{"drop", 81.2, delta(-0.61), "Coverage 81.2% (−0.6%)"},
+		{"increase", 81.2, delta(1.25), "Coverage 81.2% (+1.3%)"},
+		{"unchanged", 81.2, delta(0.01), "Coverage 81.2% (±0.0%)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatCoverage(tt.coverage, tt.delta); got != tt.want {
+				t.Errorf("formatCoverage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_coverageViolation(t *testing.T) {
+	value := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name     string
+		coverage float64
+		delta    *float64
+		min      *float64
+		maxDrop  *float64
+		wantErr  bool
+	}{
+		{"no gates", 10, value(-5), nil, nil, false},
+		{"above minimum", 80, nil, value(75), nil, false},
+		{"below minimum", 70, nil, value(75), nil, true},
+		{"allowed drop", 80, value(-0.5), nil, value(1), false},
+		{"too much drop", 80, value(-1.5), nil, value(1), true},
+		{"no baseline", 80, nil, nil, value(1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := coverageViolation(tt.coverage, tt.delta, tt.min, tt.maxDrop); (err != nil) != tt.wantErr {
+				t.Errorf("coverageViolation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_baselineStatus(t *testing.T) {
+	value := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name     string
+		statuses []commitStatus
+		wantID   int
+	}{
+		{"no statuses", nil, 0},
+		{"latest success", []commitStatus{
+			{ID: 1, Status: "success", Coverage: value(80.1)},
+			{ID: 3, Status: "success", Coverage: value(81.2)},
+			{ID: 2, Status: "success", Coverage: value(79.5)},
+		}, 3},
+		{"running and failed are skipped", []commitStatus{
+			{ID: 1, Status: "success", Coverage: value(81.2)},
+			{ID: 2, Status: "running", Coverage: value(0)},
+			{ID: 3, Status: "failed", Coverage: value(40)},
+		}, 1},
+		{"success without coverage", []commitStatus{{ID: 1, Status: "success"}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := baselineStatus(tt.statuses)
+			if (got == nil && tt.wantID != 0) || (got != nil && got.ID != tt.wantID) {
+				t.Errorf("baselineStatus() = %v, want status %d", got, tt.wantID)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// deployment is a GitLab deployment as returned by the GitLab API.
+// see also: https://docs.gitlab.com/ee/api/deployments.html
+type deployment struct {
+	ID     int    `json:"id"`
+	IID    int    `json:"iid"`
+	Ref    string `json:"ref"`
+	SHA    string `json:"sha"`
+	Status string `json:"status"`
+
+	Environment environment `json:"environment"`
+}
+
+// environment is a GitLab environment as returned by the GitLab API.
+// see also: https://docs.gitlab.com/ee/api/environments.html
+type environment struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	ExternalURL string `json:"external_url"`
+}
+
+// deploymentStatus maps the commit status state to the deployment status,
+// deployments can only be created and updated as running, success, failed or canceled.
+func deploymentStatus(state string) string {
+	if state == "pending" {
+		return "running"
+	}
+	return state
+}
+
+// upsertEnvironment creates the environment with the url, or updates the url of the existing environment.
+func upsertEnvironment(cfg config, name, externalURL string) error {
+	var environments []environment
+	path := fmt.Sprintf("projects/%s/environments", projectPath(cfg))
+	if err := apiRequest(cfg, http.MethodGet, path, url.Values{"name": {name}}, nil, "", &environments); err != nil {
+		return fmt.Errorf("failed to list environments: %s", err)
+	}
+
+	for _, e := range environments {
+		if e.Name != name {
+			continue
+		}
+		if e.ExternalURL == externalURL {
+			return nil
+		}
+		body := map[string]string{"external_url": externalURL}
+		if err := jsonRequest(cfg, http.MethodPut, fmt.Sprintf("%s/%d", path, e.ID), body, nil); err != nil {
+			return fmt.Errorf("failed to update environment: %s", err)
+		}
+		log.Printf("Updated the URL of environment %s: %s", name, externalURL)
+		return nil
+	}
+
+	body := map[string]string{"name": name, "external_url": externalURL}
+	if err := jsonRequest(cfg, http.MethodPost, path, body, nil); err != nil {
+		return fmt.Errorf("failed to create environment: %s", err)
+	}
+	log.Printf("Created environment %s: %s", name, externalURL)
+	return nil
+}
+
+func createDeployment(cfg config, status string) (deployment, error) {
+	ref, isTag := strings.TrimSpace(cfg.GitRef), false
+	if ref == "" && strings.TrimSpace(cfg.ReleaseTag) != "" {
+		ref, isTag = strings.TrimSpace(cfg.ReleaseTag), true
+	}
+
+	body := map[string]interface{}{
+		"environment": cfg.EnvironmentName,
+		"sha":         cfg.CommitHash,
+		"ref":         ref,
+		"tag":         isTag,
+		"status":      status,
+	}
+	var d deployment
+	err := jsonRequest(cfg, http.MethodPost, fmt.Sprintf("projects/%s/deployments", projectPath(cfg)), body, &d)
+	return d, err
+}
+
+func getDeployment(cfg config, id string) (deployment, error) {
+	var d deployment
+	err := apiRequest(cfg, http.MethodGet, fmt.Sprintf("projects/%s/deployments/%s", projectPath(cfg), url.PathEscape(id)), nil, nil, "", &d)
+	return d, err
+}
+
+func updateDeployment(cfg config, id int, status string) error {
+	body := map[string]string{"status": status}
+	return jsonRequest(cfg, http.MethodPut, fmt.Sprintf("projects/%s/deployments/%d", projectPath(cfg), id), body, nil)
+}
+
+// reportDeployment reports the deployment of the commit to the environment.
+// A running (or pending) build creates a running deployment, a finished build updates the deployment
+// of the given ID, or creates one and finishes it right away. The ID of the deployment is exported.
+func reportDeployment(cfg config) error {
+	if cfg.EnvironmentName == "" {
+		return fmt.Errorf("deployment mode requires an environment name")
+	}
+	if cfg.EnvironmentURL != "" {
+		if err := upsertEnvironment(cfg, cfg.EnvironmentName, cfg.EnvironmentURL); err != nil {
+			log.Warnf("%s", err)
+		}
+	}
+
+	status := deploymentStatus(getState(cfg.Status))
+
+	// The ID might be exported by the deployment of another environment earlier in the workflow.
+	var id int
+	if supplied := strings.TrimSpace(cfg.DeploymentID); supplied != "" {
+		d, err := getDeployment(cfg, supplied)
+		switch {
+		case err != nil:
+			return fmt.Errorf("failed to get deployment %s: %s", supplied, err)
+		case d.Environment.Name != cfg.EnvironmentName:
+			log.Printf("Deployment %d is a deployment to %s, creating a deployment to %s", d.ID, d.Environment.Name, cfg.EnvironmentName)
+		default:
+			id = d.ID
+		}
+	}
+
+	if id == 0 {
+		d, err := createDeployment(cfg, "running")
+		if err != nil {
+			return fmt.Errorf("failed to create deployment: %s", err)
+		}
+		id = d.ID
+		log.Donef("Created deployment %d to %s", id, cfg.EnvironmentName)
+	}
+	if err := exportEnv("GITLAB_DEPLOYMENT_ID", strconv.Itoa(id)); err != nil {
+		log.Warnf("%s", err)
+	}
+
+	if status == "running" {
+		return nil
+	}
+	if err := updateDeployment(cfg, id, status); err != nil {
+		return fmt.Errorf("failed to update deployment %d: %s", id, err)
+	}
+	log.Donef("Updated deployment %d to %s status", id, status)
+	return nil
+}
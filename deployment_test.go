@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func Test_deploymentStatus(t *testing.T) {
+	tests := []struct {
+		state string
+		want  string
+	}{
+		{state: "pending", want: "running"},
+		{state: "running", want: "running"},
+		{state: "success", want: "success"},
+		{state: "failed", want: "failed"},
+		{state: "canceled", want: "canceled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.state, func(t *testing.T) {
+			if got := deploymentStatus(tt.state); got != tt.want {
+				t.Errorf("deploymentStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
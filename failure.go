@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+const (
+	defaultLogExcerptLines = 50
+	maxLogLineLength       = 500
+)
+
+// logExcerpt returns the last lines of the log file, overlong lines are truncated.
+func logExcerpt(pth string, lines int) (string, error) {
+	if lines <= 0 {
+		lines = defaultLogExcerptLines
+	}
+
+	f, err := os.Open(pth)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Warnf("Failed to close log file (%s), error: %s", pth, err)
+		}
+	}()
+
+	var tail []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(line) > maxLogLineLength {
+			line = line[:maxLogLineLength] + "…"
+		}
+		tail = append(tail, line)
+		if len(tail) > lines {
+			tail = tail[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return strings.Join(tail, "\n"), nil
+}
+
+// logExcerptSection renders the log excerpt as a collapsed code block, or nothing if there is no excerpt.
+func logExcerptSection(excerpt string) string {
+	if excerpt == "" {
+		return ""
+	}
+	return fmt.Sprintf("\n<details><summary>Log excerpt</summary>\n\n```\n%s\n```\n\n</details>\n", excerpt)
+}
+
+// failureBody renders the failure discussion of the build.
+func failureBody(cfg config, excerpt string) string {
+	context := getContext(cfg.Context)
+
+	var b strings.Builder
+	b.WriteString(noteMarker("failure", context) + "\n")
+	fmt.Fprintf(&b, "%s **%s failed** on %s", stateIcons["failed"], context, cfg.CommitHash)
+	if cfg.TargetURL != "" {
+		fmt.Fprintf(&b, " ([build #%d](%s))", cfg.BuildNumber, cfg.TargetURL)
+	}
+	b.WriteString("\n")
+	if cfg.FailedStep != "" {
+		fmt.Fprintf(&b, "\nFailing step: **%s**\n", cfg.FailedStep)
+	}
+	b.WriteString(logExcerptSection(excerpt))
+	b.WriteString("\n_This thread is resolved automatically when a later build succeeds._\n")
+	return b.String()
+}
+
+// updateFailureDiscussion opens a resolvable discussion on the merge request when the build fails,
+// or adds the new failure to the open one. When the build succeeds, the open discussions are resolved.
+func updateFailureDiscussion(cfg config, mr *mergeRequest, state string) error {
+	if state != "success" && state != "failed" {
+		return nil
+	}
+
+	discussions, err := listMergeRequestDiscussions(cfg, mr.IID)
+	if err != nil {
+		return fmt.Errorf("failed to list merge request discussions: %s", err)
+	}
+	var open []discussion
+	for _, d := range findDiscussions(discussions, noteMarker("failure", getContext(cfg.Context))) {
+		if !d.isResolved() {
+			open = append(open, d)
+		}
+	}
+
+	if state == "success" {
+		for _, d := range open {
+			if err := replyToDiscussion(cfg, mr.IID, d.ID, fmt.Sprintf("%s Fixed in %s", stateIcons["success"], cfg.CommitHash)); err != nil {
+				log.Warnf("Failed to reply to failure discussion, error: %s", err)
+			}
+			if err := resolveDiscussion(cfg, mr.IID, d.ID); err != nil {
+				return fmt.Errorf("failed to resolve failure discussion: %s", err)
+			}
+			log.Donef("Resolved failure discussion on !%d", mr.IID)
+		}
+		return nil
+	}
+
+	var excerpt string
+	if cfg.FailureLogPath != "" {
+		if excerpt, err = logExcerpt(cfg.FailureLogPath, cfg.FailureLogLines); err != nil {
+			log.Warnf("Failed to read log file (%s), error: %s", cfg.FailureLogPath, err)
+		}
+	}
+	body := failureBody(cfg, excerpt)
+
+	if len(open) > 0 {
+		if err := replyToDiscussion(cfg, mr.IID, open[0].ID, body); err != nil {
+			return fmt.Errorf("failed to reply to failure discussion: %s", err)
+		}
+		log.Donef("Added failure to the open discussion on !%d", mr.IID)
+		return nil
+	}
+
+	if _, err := createMergeRequestDiscussion(cfg, mr.IID, url.Values{"body": {body}}); err != nil {
+		return fmt.Errorf("failed to create failure discussion: %s", err)
+	}
+	log.Donef("Opened failure discussion on !%d", mr.IID)
+	return nil
+}
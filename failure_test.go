@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_logExcerpt(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 10; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	lines = append(lines, strings.Repeat("x", maxLogLineLength+10))
+
+	pth := filepath.Join(t.TempDir(), "build.log")
+	if err := ioutil.WriteFile(pth, []byte(strings.Join(lines, "\n")), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := logExcerpt(pth, 3)
+	if err != nil {
+		t.Fatalf("logExcerpt() error = %v", err)
+	}
+	want := "line 9\nline 10\n" + strings.Repeat("x", maxLogLineLength) + "…"
+	if got != want {
+		t.Errorf("logExcerpt() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+func isFork(mr *mergeRequest) bool {
+	return mr != nil && mr.SourceProjectID != 0 && mr.TargetProjectID != 0 && mr.SourceProjectID != mr.TargetProjectID
+}
+
+// isAccessDenied returns true if the API error means the token has no access to the project.
+func isAccessDenied(err error) bool {
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+		return true
+	}
+	return false
+}
+
+// forkStatusNote is posted to the merge request when the status can not be reported to the fork project.
+func forkStatusNote(cfg config, mr *mergeRequest) string {
+	context := getContext(cfg.Context)
+	body := fmt.Sprintf("%s\n**%s**: %s for %s", noteMarker("fork", context), context, getStatusDescription(cfg), mr.SHA)
+	if cfg.TargetURL != "" {
+		body += fmt.Sprintf(" ([build](%s))", cfg.TargetURL)
+	}
+	return body + fmt.Sprintf("\n\n_The commit status could not be set, because this merge request comes from a fork (project %d) "+
+		"which the configured token has no access to. The build status is reported in this comment instead._", mr.SourceProjectID)
+}
+
+// isForkTarget returns true if the status is reported to the head of a merge request from a fork.
+func isForkTarget(target config, mr *mergeRequest) bool {
+	return isFork(mr) && target.CommitHash == mr.SHA && target.ProjectID == strconv.Itoa(mr.SourceProjectID)
+}
+
+// sendMergeRequestStatus reports the status to the target commit, if it is the head of a merge request from a fork
+// and the token has no access to the fork project, the status is reported in a merge request comment instead.
+func sendMergeRequestStatus(target config, mr *mergeRequest) (commitStatus, error) {
+	status, err := sendStatusWithRetry(target)
+	if err == nil || !isForkTarget(target, mr) || !isAccessDenied(err) {
+		return status, err
+	}
+
+	log.Warnf("No access to the fork project (%d), reporting the status in a merge request comment, error: %s", mr.SourceProjectID, err)
+	noteCfg := target
+	noteCfg.ProjectID = strconv.Itoa(mr.TargetProjectID)
+	if err := upsertMarkedNote(noteCfg, mr, noteMarker("fork", getContext(target.Context)), forkStatusNote(target, mr), true); err != nil {
+		return commitStatus{}, err
+	}
+	return commitStatus{}, nil
+}
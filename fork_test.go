@@ -0,0 +1,16 @@
+package main
+
+import "testing"
+
+func Test_forkStatusNote(t *testing.T) {
+	mr := &mergeRequest{IID: 5, SHA: "abc123", SourceProjectID: 2, TargetProjectID: 1}
+	notes := []note{
+		{ID: 1, Body: forkStatusNote(config{Context: "ci/build", Status: "running"}, mr)},
+		{ID: 2, Body: forkStatusNote(config{Context: "ci/test", Status: "running"}, mr)},
+	}
+
+	n, found := findNote(notes, noteMarker("fork", "ci/test"))
+	if !found || n.ID != 2 {
+		t.Errorf("findNote() = %v, %v, want the note of ci/test", n, found)
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// commitStatus is a commit status as returned by the GitLab API.
+// see also: https://docs.gitlab.com/ee/api/commits.html#get-the-statuses-of-a-commit
+type commitStatus struct {
+	ID          int        `json:"id"`
+	SHA         string     `json:"sha"`
+	Ref         string     `json:"ref"`
+	Status      string     `json:"status"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	TargetURL   string     `json:"target_url"`
+	Coverage    *float64   `json:"coverage"`
+	CreatedAt   time.Time  `json:"created_at"`
+	FinishedAt  *time.Time `json:"finished_at"`
+	PipelineID  int        `json:"pipeline_id"`
+}
+
+// commit is a repository commit as returned by the GitLab API.
+// see also: https://docs.gitlab.com/ee/api/commits.html#list-repository-commits
+type commit struct {
+	ID          string    `json:"id"`
+	ShortID     string    `json:"short_id"`
+	Title       string    `json:"title"`
+	Message     string    `json:"message"`
+	AuthorName  string    `json:"author_name"`
+	AuthorEmail string    `json:"author_email"`
+	CreatedAt   time.Time `json:"created_at"`
+	ParentIDs   []string  `json:"parent_ids"`
+}
+
+// pipeline is a GitLab CI pipeline as returned by the GitLab API.
+// see also: https://docs.gitlab.com/ee/api/pipelines.html#list-project-pipelines
+type pipeline struct {
+	ID        int       `json:"id"`
+	SHA       string    `json:"sha"`
+	Ref       string    `json:"ref"`
+	Status    string    `json:"status"`
+	Source    string    `json:"source"`
+	WebURL    string    `json:"web_url"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// project is a GitLab project as returned by the GitLab API.
+// see also: https://docs.gitlab.com/ee/api/projects.html#get-single-project
+type project struct {
+	ID                int    `json:"id"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	DefaultBranch     string `json:"default_branch"`
+	WebURL            string `json:"web_url"`
+}
+
+// apiError is an unsuccessful response of the GitLab API.
+type apiError struct {
+	URL        string
+	Status     string
+	StatusCode int
+	Message    string
+	Body       string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("server error: %s url: %s code: %d body: %s", e.Status, e.URL, e.StatusCode, e.Body)
+}
+
+// newAPIError creates an apiError from the response, the message is parsed from GitLab's error JSON if present.
+// GitLab reports errors either as {"message": "..."} or {"message": {"field": ["..."]}} or {"error": "..."}.
+func newAPIError(u string, resp *http.Response, body []byte) *apiError {
+	e := &apiError{URL: u, Status: resp.Status, StatusCode: resp.StatusCode, Body: string(body)}
+
+	var errResp struct {
+		Message json.RawMessage `json:"message"`
+		Error   string          `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errResp); err != nil {
+		return e
+	}
+
+	if len(errResp.Message) > 0 {
+		var msg string
+		if err := json.Unmarshal(errResp.Message, &msg); err == nil {
+			e.Message = msg
+		} else {
+			e.Message = string(errResp.Message)
+		}
+	} else {
+		e.Message = errResp.Error
+	}
+	return e
+}
+
+// getProject returns the project ID or path, the project path is parsed from the repository url if no ID is set.
+func getProject(cfg config) string {
+	if cfg.ProjectID != "" {
+		return cfg.ProjectID
+	}
+	return getRepo(cfg.RepositoryURL)
+}
+
+// projectPath returns the url escaped project ID or path of the configured repository.
+func projectPath(cfg config) string {
+	return url.PathEscape(getProject(cfg))
+}
+
+// apiRequest sends an authenticated request to the GitLab API and decodes the JSON response into v, if v is not nil.
+func apiRequest(cfg config, method, path string, query url.Values, body io.Reader, contentType string, v interface{}) error {
+	u := fmt.Sprintf("%s/%s", strings.TrimSuffix(cfg.APIURL, "/"), path)
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Add("PRIVATE-TOKEN", cfg.PrivateToken)
+	if contentType != "" {
+		req.Header.Add("Content-Type", contentType)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send the request: %s", err)
+	}
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if err := resp.Body.Close(); err != nil {
+		return err
+	}
+	if 200 > resp.StatusCode || resp.StatusCode >= 300 {
+		return newAPIError(u, resp, respBody)
+	}
+
+	if v == nil || len(respBody) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, v); err != nil {
+		return fmt.Errorf("failed to parse response: %s body: %s", err, string(respBody))
+	}
+	return nil
+}
+
+// listStatuses returns the statuses of the given commit, filtered by ref and context name if they are not empty.
+// The statuses are listed with their history, the oldest first, so every page is fetched
+// for latestStatuses to find the latest status of every context.
+func listStatuses(cfg config, sha, ref, name string) ([]commitStatus, error) {
+	var statuses []commitStatus
+	path := fmt.Sprintf("projects/%s/repository/commits/%s/statuses", projectPath(cfg), sha)
+	for page := 1; page <= maxPages; page++ {
+		query := url.Values{"all": {"true"}, "per_page": {"100"}, "page": {strconv.Itoa(page)}}
+		if ref != "" {
+			query.Set("ref", ref)
+		}
+		if name != "" {
+			query.Set("name", name)
+		}
+
+		var pageStatuses []commitStatus
+		if err := apiRequest(cfg, http.MethodGet, path, query, nil, "", &pageStatuses); err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, pageStatuses...)
+		if len(pageStatuses) < 100 {
+			break
+		}
+	}
+	return statuses, nil
+}
+
+// latestStatuses keeps only the latest status of every context and ref, as older statuses are kept by GitLab as history.
+func latestStatuses(statuses []commitStatus) []commitStatus {
+	type key struct{ name, ref string }
+	latest := map[key]int{}
+
+	var result []commitStatus
+	for _, s := range statuses {
+		k := key{s.Name, s.Ref}
+		if i, ok := latest[k]; ok {
+			if s.ID > result[i].ID {
+				result[i] = s
+			}
+			continue
+		}
+		latest[k] = len(result)
+		result = append(result, s)
+	}
+	return result
+}
+
+// listCommits returns the latest commits of the given ref, at most limit commits are returned.
+func listCommits(cfg config, ref string, limit int) ([]commit, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+	query := url.Values{"per_page": {strconv.Itoa(limit)}}
+	if ref != "" {
+		query.Set("ref_name", ref)
+	}
+
+	var commits []commit
+	path := fmt.Sprintf("projects/%s/repository/commits", projectPath(cfg))
+	if err := apiRequest(cfg, http.MethodGet, path, query, nil, "", &commits); err != nil {
+		return nil, err
+	}
+	return commits, nil
+}
+
+// getCommit returns the commit of the given hash.
+func getCommit(cfg config, sha string) (commit, error) {
+	var c commit
+	path := fmt.Sprintf("projects/%s/repository/commits/%s", projectPath(cfg), sha)
+	err := apiRequest(cfg, http.MethodGet, path, nil, nil, "", &c)
+	return c, err
+}
+
+// listPipelines returns the pipelines of the given commit, the newest first, filtered by ref if it is not empty.
+func listPipelines(cfg config, sha, ref string) ([]pipeline, error) {
+	query := url.Values{"sha": {sha}, "order_by": {"id"}, "sort": {"desc"}}
+	if ref != "" {
+		query.Set("ref", ref)
+	}
+
+	var pipelines []pipeline
+	path := fmt.Sprintf("projects/%s/pipelines", projectPath(cfg))
+	if err := apiRequest(cfg, http.MethodGet, path, query, nil, "", &pipelines); err != nil {
+		return nil, err
+	}
+	return pipelines, nil
+}
+
+func getProjectInfo(cfg config) (project, error) {
+	var p project
+	err := apiRequest(cfg, http.MethodGet, fmt.Sprintf("projects/%s", projectPath(cfg)), nil, nil, "", &p)
+	return p, err
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_listStatuses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.URL.EscapedPath(), "/projects/group%2Fapp/repository/commits/abc123/statuses"; got != want {
+			t.Errorf("unexpected path: %s, want %s", got, want)
+		}
+		if r.URL.Query().Get("all") != "true" {
+			t.Errorf("statuses are listed without history")
+		}
+		var statuses []commitStatus
+		switch r.URL.Query().Get("page") {
+		case "1":
+			for id := 1; id <= 100; id++ {
+				statuses = append(statuses, commitStatus{ID: id, Name: "ci/test", Ref: "main", Status: "running"})
+			}
+		case "2":
+			statuses = append(statuses, commitStatus{ID: 101, Name: "ci/test", Ref: "main", Status: "success"})
+		default:
+			t.Errorf("unexpected page: %s", r.URL.Query().Get("page"))
+		}
+		if err := json.NewEncoder(w).Encode(statuses); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	cfg := config{APIURL: server.URL, ProjectID: "group/app"}
+	statuses, err := listStatuses(cfg, "abc123", "", "")
+	if err != nil {
+		t.Fatalf("listStatuses() error = %v", err)
+	}
+	if len(statuses) != 101 {
+		t.Fatalf("listStatuses() returned %d statuses, want 101", len(statuses))
+	}
+
+	latest := latestStatuses(statuses)
+	if len(latest) != 1 || latest[0].Status != "success" {
+		t.Errorf("latestStatuses() = %v, want the success status of page 2", latest)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// issue is a GitLab issue as returned by the GitLab API.
+// see also: https://docs.gitlab.com/ee/api/issues.html
+type issue struct {
+	ID          int    `json:"id"`
+	IID         int    `json:"iid"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	State       string `json:"state"`
+	WebURL      string `json:"web_url"`
+}
+
+// listOpenIssues returns the open issues of the project with all the labels.
+func listOpenIssues(cfg config, labels []string) ([]issue, error) {
+	var issues []issue
+	path := fmt.Sprintf("projects/%s/issues", projectPath(cfg))
+	for page := 1; page <= maxPages; page++ {
+		query := url.Values{"state": {"opened"}, "per_page": {"100"}, "page": {strconv.Itoa(page)}}
+		if len(labels) > 0 {
+			query.Set("labels", strings.Join(labels, ","))
+		}
+
+		var pageIssues []issue
+		if err := apiRequest(cfg, http.MethodGet, path, query, nil, "", &pageIssues); err != nil {
+			return nil, err
+		}
+		issues = append(issues, pageIssues...)
+		if len(pageIssues) < 100 {
+			break
+		}
+	}
+	return issues, nil
+}
+
+// findIssue returns the issue whose description contains the marker.
+func findIssue(issues []issue, marker string) *issue {
+	for i := range issues {
+		if strings.Contains(issues[i].Description, marker) {
+			return &issues[i]
+		}
+	}
+	return nil
+}
+
+// createIssueNote adds a comment to the issue.
+func createIssueNote(cfg config, iid int, body string) error {
+	form := url.Values{"body": {body}}
+	path := fmt.Sprintf("projects/%s/issues/%d/notes", projectPath(cfg), iid)
+	return apiRequest(cfg, http.MethodPost, path, nil, strings.NewReader(form.Encode()), "application/x-www-form-urlencoded", nil)
+}
+
+// findUserByEmail returns the ID of the user with the public email, or 0 if there is no such user.
+func findUserByEmail(cfg config, email string) int {
+	if email == "" {
+		return 0
+	}
+	var users []user
+	if err := apiRequest(cfg, http.MethodGet, "users", url.Values{"search": {email}}, nil, "", &users); err != nil || len(users) != 1 {
+		return 0
+	}
+	return users[0].ID
+}
+
+// buildLink renders the link of the build, or its number if there is no build url.
+func buildLink(cfg config) string {
+	if cfg.TargetURL == "" {
+		return fmt.Sprintf("build #%d", cfg.BuildNumber)
+	}
+	return fmt.Sprintf("[build #%d](%s)", cfg.BuildNumber, cfg.TargetURL)
+}
+
+// trackingIssueBody renders the description of the tracking issue, and the comment of repeated failures.
+func trackingIssueBody(cfg config, c commit, excerpt string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s **%s failed** on `%s` at %s (%s)\n", stateIcons["failed"], getContext(cfg.Context), cfg.GitRef, cfg.CommitHash, buildLink(cfg))
+	if c.Title != "" {
+		fmt.Fprintf(&b, "\nCommit: %s by %s\n", c.Title, c.AuthorName)
+	}
+	if cfg.FailedStep != "" {
+		fmt.Fprintf(&b, "\nFailing step: **%s**\n", cfg.FailedStep)
+	}
+	b.WriteString(logExcerptSection(excerpt))
+	return b.String()
+}
+
+// updateTrackingIssue opens a tracking issue when a build of the default branch fails,
+// assigned to the author of the commit, or comments the new failure on the open one.
+// When the branch turns green again, the issue is closed.
+func updateTrackingIssue(cfg config, state string) error {
+	p, err := getProjectInfo(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to get project: %s", err)
+	}
+	if cfg.GitRef != p.DefaultBranch {
+		return nil
+	}
+
+	context := getContext(cfg.Context)
+	marker := noteMarker("issue", fmt.Sprintf("%s:%s", context, cfg.GitRef))
+	issues, err := listOpenIssues(cfg, cfg.TrackingIssueLabels)
+	if err != nil {
+		return fmt.Errorf("failed to list issues: %s", err)
+	}
+	existing := findIssue(issues, marker)
+
+	if state == "success" {
+		if existing == nil {
+			return nil
+		}
+		body := fmt.Sprintf("%s Fixed by %s (%s)", stateIcons["success"], cfg.CommitHash, buildLink(cfg))
+		if err := createIssueNote(cfg, existing.IID, body); err != nil {
+			return fmt.Errorf("failed to comment on issue #%d: %s", existing.IID, err)
+		}
+		form := url.Values{"state_event": {"close"}}
+		path := fmt.Sprintf("projects/%s/issues/%d", projectPath(cfg), existing.IID)
+		if err := apiRequest(cfg, http.MethodPut, path, nil, strings.NewReader(form.Encode()), "application/x-www-form-urlencoded", nil); err != nil {
+			return fmt.Errorf("failed to close issue #%d: %s", existing.IID, err)
+		}
+		log.Donef("Closed tracking issue #%d", existing.IID)
+		return nil
+	}
+	if state != "failed" {
+		return nil
+	}
+
+	c, err := getCommit(cfg, cfg.CommitHash)
+	if err != nil {
+		log.Warnf("Failed to get commit %s, error: %s", shortSHA(cfg.CommitHash), err)
+	}
+	var excerpt string
+	if cfg.FailureLogPath != "" {
+		if excerpt, err = logExcerpt(cfg.FailureLogPath, cfg.FailureLogLines); err != nil {
+			log.Warnf("Failed to read log file, error: %s", err)
+		}
+	}
+	body := trackingIssueBody(cfg, c, excerpt)
+
+	if existing != nil {
+		if err := createIssueNote(cfg, existing.IID, body); err != nil {
+			return fmt.Errorf("failed to comment on issue #%d: %s", existing.IID, err)
+		}
+		log.Donef("Added the failure to tracking issue #%d: %s", existing.IID, existing.WebURL)
+		return nil
+	}
+
+	form := url.Values{
+		"title":       {fmt.Sprintf("%s is failing on %s", context, cfg.GitRef)},
+		"description": {marker + "\n" + body + "\n_This issue is closed automatically when a later build succeeds._\n"},
+		"labels":      {strings.Join(cfg.TrackingIssueLabels, ",")},
+	}
+	if id := findUserByEmail(cfg, c.AuthorEmail); id != 0 {
+		form.Set("assignee_ids", strconv.Itoa(id))
+	}
+	var created issue
+	path := fmt.Sprintf("projects/%s/issues", projectPath(cfg))
+	if err := apiRequest(cfg, http.MethodPost, path, nil, strings.NewReader(form.Encode()), "application/x-www-form-urlencoded", &created); err != nil {
+		return fmt.Errorf("failed to open tracking issue: %s", err)
+	}
+	log.Donef("Opened tracking issue #%d: %s", created.IID, created.WebURL)
+	return nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_findIssue(t *testing.T) {
+	marker := noteMarker("issue", "ci/bitrise:main")
+	issues := []issue{
+		{IID: 1, Description: "Crash on launch"},
+		{IID: 2, Description: noteMarker("issue", "ci/bitrise:develop") + "\nfailed"},
+		{IID: 3, Description: marker + "\nfailed"},
+	}
+
+	if got := findIssue(issues, marker); got == nil || got.IID != 3 {
+		t.Errorf("findIssue() = %v, want issue 3", got)
+	}
+	if got := findIssue(issues[:2], marker); got != nil {
+		t.Errorf("findIssue() = %v, want nil", got)
+	}
+}
+
+func Test_trackingIssueBody(t *testing.T) {
+	cfg := config{Context: "ci/bitrise", GitRef: "main", CommitHash: "abc123", BuildNumber: 42, TargetURL: "https://app.bitrise.io/build/1", FailedStep: "Xcode Test"}
+	c := commit{Title: "Bump dependencies", AuthorName: "Sam"}
+
+	body := trackingIssueBody(cfg, c, "error: tests failed")
+	for _, want := range []string{
+		"**ci/bitrise failed** on `main` at abc123 ([build #42](https://app.bitrise.io/build/1))",
+		"Commit: Bump dependencies by Sam",
+		"Failing step: **Xcode Test**",
+		"error: tests failed",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("trackingIssueBody() = %v, want it to contain %v", body, want)
+		}
+	}
+}
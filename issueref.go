@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+const (
+	maxReferencedIssues = 10
+	issueNoteInterval   = time.Second
+)
+
+// issueReferencePattern matches GitLab issue references like #123, project#123 and group/subgroup/project#123.
+// see also: https://docs.gitlab.com/ee/user/markdown.html#gitlab-specific-references
+var issueReferencePattern = regexp.MustCompile(`(?:^|[\s(\[,;:])((?:[\w.-]+/)*[\w.-]+)?#(\d+)\b`)
+
+// issueReference is an issue referenced by a commit message, Project is empty for issues of the current project.
+type issueReference struct {
+	Project string
+	IID     int
+}
+
+func (r issueReference) String() string {
+	return fmt.Sprintf("%s#%d", r.Project, r.IID)
+}
+
+// parseIssueReferences returns the distinct issues referenced by the message, in order of appearance.
+// A reference without namespace (project#123) is relative to the current project's namespace.
+func parseIssueReferences(message, currentProject string) []issueReference {
+	var refs []issueReference
+	seen := map[issueReference]bool{}
+	for _, m := range issueReferencePattern.FindAllStringSubmatch(message, -1) {
+		iid, err := strconv.Atoi(m[2])
+		if err != nil || iid == 0 {
+			continue
+		}
+
+		project := m[1]
+		if project != "" && !strings.Contains(project, "/") {
+			if idx := strings.LastIndex(currentProject, "/"); idx > 0 {
+				project = currentProject[:idx] + "/" + project
+			}
+		}
+		if project == currentProject {
+			project = ""
+		}
+
+		ref := issueReference{Project: project, IID: iid}
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// issueRefBody renders the note of the build result on a referenced issue.
+func issueRefBody(cfg config, state string) string {
+	var b strings.Builder
+	b.WriteString(noteMarker("issue-ref", fmt.Sprintf("%s:%d", getContext(cfg.Context), cfg.BuildNumber)) + "\n")
+	fmt.Fprintf(&b, "%s **%s: %s** on %s (%s)\n", stateIcons[state], getContext(cfg.Context), strings.Title(state), cfg.CommitHash, buildLink(cfg))
+
+	var links []string
+	for _, u := range cfg.ArtifactURLs {
+		if u = strings.TrimSpace(u); u != "" {
+			links = append(links, fmt.Sprintf("[Install](%s)", u))
+		}
+	}
+	if len(links) > 0 {
+		fmt.Fprintf(&b, "\n%s\n", strings.Join(links, " · "))
+	}
+	return b.String()
+}
+
+// hasIssueNote returns whether the latest comments of the issue contain the marker.
+func hasIssueNote(cfg config, iid int, marker string) (bool, error) {
+	var notes []note
+	query := url.Values{"sort": {"desc"}, "order_by": {"created_at"}, "per_page": {"100"}}
+	path := fmt.Sprintf("projects/%s/issues/%d/notes", projectPath(cfg), iid)
+	if err := apiRequest(cfg, http.MethodGet, path, query, nil, "", &notes); err != nil {
+		return false, err
+	}
+	for _, n := range notes {
+		if strings.Contains(n.Body, marker) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// commitMessage returns the configured commit message, or fetches it from GitLab if it is not set.
+func commitMessage(cfg config) (string, error) {
+	if strings.TrimSpace(cfg.CommitMessage) != "" {
+		return cfg.CommitMessage, nil
+	}
+	c, err := getCommit(cfg, cfg.CommitHash)
+	if err != nil {
+		return "", fmt.Errorf("failed to get commit %s: %s", shortSHA(cfg.CommitHash), err)
+	}
+	return c.Message, nil
+}
+
+// projectFullPath returns the path with namespace of the configured project, as the project might be configured by its numeric ID.
+// If the project can not be fetched, the path of the repository is used.
+func projectFullPath(cfg config) string {
+	p, err := getProjectInfo(cfg)
+	if err != nil || p.PathWithNamespace == "" {
+		log.Warnf("Failed to get project, using the path of the repository url, error: %v", err)
+		return getRepo(cfg.RepositoryURL)
+	}
+	return p.PathWithNamespace
+}
+
+// commentReferencedIssues posts the build result on the issues referenced by the commit message.
+// At most maxReferencedIssues issues are commented, each one once per build.
+func commentReferencedIssues(cfg config, state string) error {
+	message, err := commitMessage(cfg)
+	if err != nil {
+		return err
+	}
+
+	refs := parseIssueReferences(message, projectFullPath(cfg))
+	if len(refs) == 0 {
+		return nil
+	}
+	if len(refs) > maxReferencedIssues {
+		log.Warnf("The commit message references %d issues, only the first %d are commented", len(refs), maxReferencedIssues)
+		refs = refs[:maxReferencedIssues]
+	}
+
+	marker := noteMarker("issue-ref", fmt.Sprintf("%s:%d", getContext(cfg.Context), cfg.BuildNumber))
+	body := issueRefBody(cfg, state)
+	for i, ref := range refs {
+		if i > 0 {
+			time.Sleep(issueNoteInterval)
+		}
+
+		// Issues of other projects are commented with the same token, if it has access to them.
+		issueCfg := cfg
+		if ref.Project != "" {
+			issueCfg.ProjectID = ref.Project
+		}
+		if found, err := hasIssueNote(issueCfg, ref.IID, marker); err != nil {
+			log.Warnf("Failed to get comments of issue %s, error: %s", ref, err)
+			continue
+		} else if found {
+			log.Printf("Issue %s is already commented by this build", ref)
+			continue
+		}
+
+		if err := createIssueNote(issueCfg, ref.IID, body); err != nil {
+			log.Warnf("Failed to comment on issue %s, error: %s", ref, err)
+			continue
+		}
+		log.Donef("Commented the build result on issue %s", ref)
+	}
+	return nil
+}
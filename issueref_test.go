@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func Test_parseIssueReferences(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    []issueReference
+	}{
+		{
+			name:    "current project",
+			message: "Fix crash on launch\n\nCloses #123, refs #7",
+			want:    []issueReference{{IID: 123}, {IID: 7}},
+		},
+		{
+			name:    "cross-project references",
+			message: "Refs group/proj#45 and group/sub/other#3 (see also mobile#9)",
+			want:    []issueReference{{Project: "group/proj", IID: 45}, {Project: "group/sub/other", IID: 3}, {Project: "group/mobile", IID: 9}},
+		},
+		{
+			name:    "duplicates and the current project's full path",
+			message: "Closes #12\nCloses group/app#12",
+			want:    []issueReference{{IID: 12}},
+		},
+		{
+			name:    "no references",
+			message: "Merge !34, see https://example.com/page#12 and issue#abc",
+			want:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseIssueReferences(tt.message, "group/app"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseIssueReferences() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_projectFullPath(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/projects/42" {
+			http.NotFound(w, r)
+			return
+		}
+		if err := json.NewEncoder(w).Encode(project{ID: 42, PathWithNamespace: "group/app"}); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		projectID string
+		want      string
+	}{
+		{"numeric project id", "42", "group/app"},
+		{"unknown project", "43", "group/mirror"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config{APIURL: server.URL, ProjectID: tt.projectID, RepositoryURL: "https://gitlab.com/group/mirror.git"}
+			if got := projectFullPath(cfg); got != tt.want {
+				t.Errorf("projectFullPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
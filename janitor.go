@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+const defaultJanitorMaxAge = 3 * time.Hour
+
+// staleStatus is a pending or running status which was not updated for too long.
+type staleStatus struct {
+	Commit commit
+	Status commitStatus
+	Age    time.Duration
+}
+
+func isUnfinished(state string) bool {
+	return state == "pending" || state == "running"
+}
+
+// findStaleStatuses returns the latest statuses of the given contexts which are pending or running for longer than maxAge.
+func findStaleStatuses(c commit, statuses []commitStatus, contexts []string, maxAge time.Duration, now time.Time) []staleStatus {
+	var stale []staleStatus
+	for _, s := range latestStatuses(statuses) {
+		if !containsString(contexts, s.Name) || !isUnfinished(s.Status) {
+			continue
+		}
+		if age := now.Sub(s.CreatedAt); age > maxAge {
+			stale = append(stale, staleStatus{Commit: c, Status: s, Age: age})
+		}
+	}
+	return stale
+}
+
+// cancelStatus sets the given status of the commit to canceled, keeping its context, ref and target url.
+func cancelStatus(cfg config, sha string, s commitStatus, description string) error {
+	cfg.SkipOutdated = false
+	cfg.CommitHash = sha
+	cfg.GitRef = s.Ref
+	cfg.Context = s.Name
+	cfg.TargetURL = s.TargetURL
+	cfg.PipelineID = 0
+	cfg.Status = "canceled"
+	cfg.Description = description
+
+	_, err := sendStatusWithRetry(cfg)
+	return err
+}
+
+func getJanitorContexts(cfg config) []string {
+	if len(cfg.JanitorContexts) > 0 {
+		return cfg.JanitorContexts
+	}
+	return []string{getContext(cfg.Context)}
+}
+
+func getJanitorRefs(cfg config) []string {
+	if len(cfg.JanitorRefs) > 0 {
+		return cfg.JanitorRefs
+	}
+	return []string{cfg.GitRef}
+}
+
+// cleanupStaleStatuses cancels the statuses of recent commits which are stuck in pending or running state,
+// for example because the build which reported them was aborted.
+func cleanupStaleStatuses(cfg config) error {
+	maxAge, err := parseDuration(cfg.JanitorMaxAge, defaultJanitorMaxAge)
+	if err != nil {
+		return err
+	}
+	contexts := getJanitorContexts(cfg)
+
+	seen := map[string]bool{}
+	var stale []staleStatus
+	for _, ref := range getJanitorRefs(cfg) {
+		commits, err := listCommits(cfg, ref, cfg.JanitorCommitLimit)
+		if err != nil {
+			return fmt.Errorf("failed to list commits of %s: %s", ref, err)
+		}
+
+		for _, c := range commits {
+			if seen[c.ID] {
+				continue
+			}
+			seen[c.ID] = true
+
+			statuses, err := listStatuses(cfg, c.ID, "", "")
+			if err != nil {
+				return fmt.Errorf("failed to list statuses of %s: %s", c.ShortID, err)
+			}
+			stale = append(stale, findStaleStatuses(c, statuses, contexts, maxAge, time.Now())...)
+		}
+	}
+
+	if len(stale) == 0 {
+		log.Donef("No statuses found in %v pending or running for longer than %s", contexts, maxAge)
+		return nil
+	}
+
+	log.Infof("Found %d stale status(es):", len(stale))
+	for _, s := range stale {
+		log.Printf("- %s %s (%s) context: %s status: %s age: %s", s.Commit.ShortID, s.Commit.Title, s.Status.Ref, s.Status.Name, s.Status.Status, s.Age.Round(time.Minute))
+	}
+	if cfg.DryRun {
+		log.Warnf("Dry run, no status was changed")
+		return nil
+	}
+
+	var failed int
+	for _, s := range stale {
+		desc := fmt.Sprintf("Canceled: %s for %s, the build was probably aborted", s.Status.Status, s.Age.Round(time.Minute))
+		if err := cancelStatus(cfg, s.Commit.ID, s.Status, desc); err != nil {
+			log.Warnf("Failed to cancel status of %s (%s), error: %s", s.Commit.ShortID, s.Status.Name, err)
+			failed++
+			continue
+		}
+		log.Donef("Canceled status of %s (%s)", s.Commit.ShortID, s.Status.Name)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to cancel %d of %d stale status(es)", failed, len(stale))
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_findStaleStatuses(t *testing.T) {
+	now := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+	c := commit{ID: "be6a506812974b4325c950f6d123a22199356371"}
+	tests := []struct {
+		name     string
+		statuses []commitStatus
+		want     int
+	}{
+		{"no statuses", nil, 0},
+		{"stale running", []commitStatus{{ID: 1, Name: "Bitrise", Ref: "main", Status: "running", CreatedAt: now.Add(-4 * time.Hour)}}, 1},
+		{"stale pending", []commitStatus{{ID: 1, Name: "Bitrise", Ref: "main", Status: "pending", CreatedAt: now.Add(-4 * time.Hour)}}, 1},
+		{"recent running", []commitStatus{{ID: 1, Name: "Bitrise", Ref: "main", Status: "running", CreatedAt: now.Add(-time.Hour)}}, 0},
+		{"finished", []commitStatus{{ID: 1, Name: "Bitrise", Ref: "main", Status: "success", CreatedAt: now.Add(-4 * time.Hour)}}, 0},
+		{"other context", []commitStatus{{ID: 1, Name: "other", Ref: "main", Status: "running", CreatedAt: now.Add(-4 * time.Hour)}}, 0},
+		{"finished later", []commitStatus{
+			{ID: 2, Name: "Bitrise", Ref: "main", Status: "success", CreatedAt: now.Add(-3 * time.Hour)},
+			{ID: 1, Name: "Bitrise", Ref: "main", Status: "running", CreatedAt: now.Add(-4 * time.Hour)},
+		}, 0},
+	}
+	// A context stuck in running after more than a page of history of other contexts.
+	var history []commitStatus
+	for id := 1; id <= 150; id++ {
+		history = append(history, commitStatus{ID: id, Name: "other", Ref: "main", Status: "success", CreatedAt: now.Add(-5 * time.Hour)})
+	}
+	tests = append(tests, struct {
+		name     string
+		statuses []commitStatus
+		want     int
+	}{"stuck after long history", append(history, commitStatus{ID: 151, Name: "Bitrise", Ref: "main", Status: "running", CreatedAt: now.Add(-4 * time.Hour)}), 1})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findStaleStatuses(c, tt.statuses, []string{"Bitrise"}, 3*time.Hour, now); len(got) != tt.want {
+				t.Errorf("findStaleStatuses() = %v, want %d stale statuses", got, tt.want)
+			}
+		})
+	}
+}
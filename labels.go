@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// parseStateLabels parses the `state=label` inputs into the label of each state.
+func parseStateLabels(values []string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		idx := strings.Index(v, "=")
+		if idx <= 0 || strings.TrimSpace(v[idx+1:]) == "" {
+			return nil, fmt.Errorf("invalid state label, expected state=label: %s", v)
+		}
+		state := strings.TrimSpace(v[:idx])
+		if _, ok := statePriority[state]; !ok {
+			return nil, fmt.Errorf("invalid state label, unknown state: %s", state)
+		}
+		labels[state] = strings.TrimSpace(v[idx+1:])
+	}
+	return labels, nil
+}
+
+// labelChanges returns the label of the state which the merge request is missing,
+// and the labels of the other states which the merge request has.
+func labelChanges(current []string, labels map[string]string, state string) (add, remove []string) {
+	has := map[string]bool{}
+	for _, l := range current {
+		has[l] = true
+	}
+
+	want := labels[state]
+	if want != "" && !has[want] {
+		add = append(add, want)
+	}
+	for _, l := range labels {
+		if l != want && has[l] && !containsString(remove, l) {
+			remove = append(remove, l)
+		}
+	}
+	sort.Strings(remove)
+	return add, remove
+}
+
+// updateStateLabels adds the label of the build state to the merge request and removes the labels of the other states
+// in a single update, the merge request's other labels are left alone.
+func updateStateLabels(cfg config, mr *mergeRequest, state string) error {
+	labels, err := parseStateLabels(cfg.StateLabels)
+	if err != nil {
+		return err
+	}
+	add, remove := labelChanges(mr.Labels, labels, state)
+	if len(add) == 0 && len(remove) == 0 {
+		return nil
+	}
+
+	form := url.Values{"add_labels": {strings.Join(add, ",")}, "remove_labels": {strings.Join(remove, ",")}}
+	path := fmt.Sprintf("projects/%s/merge_requests/%d", projectPath(cfg), mr.IID)
+	if err := apiRequest(cfg, http.MethodPut, path, nil, strings.NewReader(form.Encode()), "application/x-www-form-urlencoded", nil); err != nil {
+		return fmt.Errorf("failed to update labels of merge request !%d: %s", mr.IID, err)
+	}
+	log.Donef("Updated labels of merge request !%d, added: %v, removed: %v", mr.IID, add, remove)
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parseStateLabels(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:   "scoped labels",
+			values: []string{"success=ci::passed", " failed = ci::failed ", "running=ci::running", ""},
+			want:   map[string]string{"success": "ci::passed", "failed": "ci::failed", "running": "ci::running"},
+		},
+		{name: "missing label", values: []string{"success="}, wantErr: true},
+		{name: "unknown state", values: []string{"passed=ci::passed"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseStateLabels(tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseStateLabels() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseStateLabels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_labelChanges(t *testing.T) {
+	labels := map[string]string{"success": "ci::passed", "failed": "ci::failed", "running": "ci::running"}
+	tests := []struct {
+		name       string
+		current    []string
+		state      string
+		wantAdd    []string
+		wantRemove []string
+	}{
+		{name: "running to success", current: []string{"bug", "ci::running"}, state: "success", wantAdd: []string{"ci::passed"}, wantRemove: []string{"ci::running"}},
+		{name: "up to date", current: []string{"ci::failed"}, state: "failed"},
+		{name: "state without label", current: []string{"ci::failed", "ci::running"}, state: "canceled", wantRemove: []string{"ci::failed", "ci::running"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			add, remove := labelChanges(tt.current, labels, tt.state)
+			if !reflect.DeepEqual(add, tt.wantAdd) || !reflect.DeepEqual(remove, tt.wantRemove) {
+				t.Errorf("labelChanges() = %v, %v, want %v, %v", add, remove, tt.wantAdd, tt.wantRemove)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// buildState is persisted by the start mode, so that the finish mode can report the final status
+// to the same project, commit and contexts.
+type buildState struct {
+	Project    string   `json:"project"`
+	CommitHash string   `json:"commit_hash"`
+	GitRef     string   `json:"git_ref"`
+	PipelineID int      `json:"pipeline_id"`
+	Contexts   []string `json:"contexts"`
+}
+
+func getStatePath(cfg config) string {
+	if cfg.StatePath != "" {
+		return cfg.StatePath
+	}
+	return filepath.Join(os.TempDir(), "gitlab-status-state.json")
+}
+
+// loadState reads the persisted build state, it returns nil if the state file does not exist.
+func loadState(pth string) (*buildState, error) {
+	b, err := ioutil.ReadFile(pth)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var state buildState
+	if err := json.Unmarshal(b, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file (%s): %s", pth, err)
+	}
+	return &state, nil
+}
+
+func saveState(pth string, state buildState) error {
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(pth), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(pth, b, 0600)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeState adds the started context to the previous state of the same project and commit,
+// or starts a new state if the previous one belongs to another build.
+func mergeState(previous *buildState, cfg config, pipelineID int) buildState {
+	var state buildState
+	if previous != nil && previous.Project == getProject(cfg) && previous.CommitHash == cfg.CommitHash {
+		state = *previous
+	} else {
+		state = buildState{
+			Project:    getProject(cfg),
+			CommitHash: cfg.CommitHash,
+			GitRef:     cfg.GitRef,
+		}
+	}
+
+	if pipelineID > 0 {
+		state.PipelineID = pipelineID
+	} else if cfg.PipelineID > 0 {
+		state.PipelineID = cfg.PipelineID
+	}
+	if context := getContext(cfg.Context); !containsString(state.Contexts, context) {
+		state.Contexts = append(state.Contexts, context)
+	}
+	return state
+}
+
+// startBuild reports the running status and saves the build state for the finish mode.
+// Starting multiple contexts for the same commit adds them to the same state.
+func startBuild(cfg config, mr *mergeRequest) error {
+	cfg = statusTargets(cfg, mr)[0]
+	cfg.Status = "running"
+	status, err := sendMergeRequestStatus(cfg, mr)
+	if err != nil {
+		return err
+	}
+
+	pth := getStatePath(cfg)
+	previous, err := loadState(pth)
+	if err != nil {
+		log.Warnf("Failed to load previous state, starting a new one, error: %s", err)
+	}
+	state := mergeState(previous, cfg, status.PipelineID)
+
+	if err := saveState(pth, state); err != nil {
+		return fmt.Errorf("failed to save state: %s", err)
+	}
+	log.Donef("Started %d context(s) on %s, state saved to %s", len(state.Contexts), state.CommitHash, pth)
+
+	if cfg.CancelSuperseded {
+		if err := cancelSupersededStatuses(cfg); err != nil {
+			log.Warnf("Failed to cancel statuses of superseded commits, error: %s", err)
+		}
+	}
+	return nil
+}
+
+// finishBuild reports the final status to every context started by the start mode.
+// If no state was saved, it reports the status of the configured context.
+func finishBuild(cfg config, mr *mergeRequest) error {
+	pth := getStatePath(cfg)
+	state, err := loadState(pth)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		log.Warnf("No state found at %s, was the Step run in start mode? Reporting status for the configured context", pth)
+		return reportStatus(cfg, mr)
+	}
+	if target := statusTargets(cfg, mr)[0]; !isStateOf(*state, target) {
+		log.Warnf("The state at %s belongs to another build (%s at %s), reporting status for the configured context", pth, state.Project, shortSHA(state.CommitHash))
+		return reportStatus(cfg, mr)
+	}
+
+	failed := finishContexts(cfg, *state, pth, func(ctxCfg config) error {
+		_, err := sendMergeRequestStatus(ctxCfg, mr)
+		return err
+	})
+	if len(failed) > 0 {
+		return fmt.Errorf("could not finish %d of %d context(s), they might be left in running state: %s", len(failed), len(state.Contexts), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// isStateOf returns whether the state was saved by the start mode of the same build,
+// as the default state path can be shared by other builds.
+func isStateOf(state buildState, cfg config) bool {
+	return state.Project == getProject(cfg) && state.CommitHash == cfg.CommitHash
+}
+
+// finishContexts sends the final status of every context of the state, the state file is removed
+// only if every context is finished, so a later run can retry the failed ones.
+func finishContexts(cfg config, state buildState, pth string, send func(config) error) (failed []string) {
+	cfg.ProjectID = state.Project
+	cfg.CommitHash = state.CommitHash
+	cfg.GitRef = state.GitRef
+	cfg.PipelineID = state.PipelineID
+
+	for _, context := range state.Contexts {
+		ctxCfg := cfg
+		ctxCfg.Context = context
+		if err := send(ctxCfg); err != nil {
+			log.Warnf("Failed to finish context %s, error: %s", context, err)
+			failed = append(failed, context)
+			continue
+		}
+		log.Donef("Finished context %s with %s status", context, getState(cfg.Status))
+	}
+
+	if len(failed) > 0 {
+		return failed
+	}
+	if err := os.Remove(pth); err != nil {
+		log.Warnf("Failed to remove state file (%s), error: %s", pth, err)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_loadState(t *testing.T) {
+	dir := t.TempDir()
+	saved := buildState{Project: "group/app", CommitHash: "abc123", GitRef: "main", PipelineID: 7, Contexts: []string{"ci/build"}}
+	if err := saveState(filepath.Join(dir, "saved", "state.json"), saved); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "invalid.json"), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		pth     string
+		want    *buildState
+		wantErr bool
+	}{
+		{name: "missing file", pth: filepath.Join(dir, "missing.json"), want: nil},
+		{name: "saved state", pth: filepath.Join(dir, "saved", "state.json"), want: &saved},
+		{name: "invalid state", pth: filepath.Join(dir, "invalid.json"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadState(tt.pth)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadState() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadState() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_mergeState(t *testing.T) {
+	cfg := config{ProjectID: "group/app", CommitHash: "abc123", GitRef: "main", Context: "ci/test"}
+	previous := &buildState{Project: "group/app", CommitHash: "abc123", GitRef: "main", PipelineID: 7, Contexts: []string{"ci/build"}}
+	tests := []struct {
+		name       string
+		previous   *buildState
+		cfg        config
+		pipelineID int
+		want       buildState
+	}{
+		{
+			name: "first start",
+			cfg:  cfg,
+			want: buildState{Project: "group/app", CommitHash: "abc123", GitRef: "main", Contexts: []string{"ci/test"}},
+		},
+		{
+			name:     "contexts merged",
+			previous: previous,
+			cfg:      cfg,
+			want:     buildState{Project: "group/app", CommitHash: "abc123", GitRef: "main", PipelineID: 7, Contexts: []string{"ci/build", "ci/test"}},
+		},
+		{
+			name:     "context started again",
+			previous: previous,
+			cfg:      config{ProjectID: "group/app", CommitHash: "abc123", GitRef: "main", Context: "ci/build"},
+			want:     *previous,
+		},
+		{
+			name:       "pipeline of the status",
+			previous:   previous,
+			cfg:        cfg,
+			pipelineID: 9,
+			want:       buildState{Project: "group/app", CommitHash: "abc123", GitRef: "main", PipelineID: 9, Contexts: []string{"ci/build", "ci/test"}},
+		},
+		{
+			name:     "other commit resets the state",
+			previous: previous,
+			cfg:      config{ProjectID: "group/app", CommitHash: "def456", GitRef: "main", Context: "ci/test"},
+			want:     buildState{Project: "group/app", CommitHash: "def456", GitRef: "main", Contexts: []string{"ci/test"}},
+		},
+		{
+			name:     "other project resets the state",
+			previous: previous,
+			cfg:      config{ProjectID: "group/lib", CommitHash: "abc123", GitRef: "main", Context: "ci/test"},
+			want:     buildState{Project: "group/lib", CommitHash: "abc123", GitRef: "main", Contexts: []string{"ci/test"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var prev *buildState
+			if tt.previous != nil {
+				p := *tt.previous
+				p.Contexts = append([]string(nil), tt.previous.Contexts...)
+				prev = &p
+			}
+			if got := mergeState(prev, tt.cfg, tt.pipelineID); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeState() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_finishContexts(t *testing.T) {
+	state := buildState{Project: "group/app", CommitHash: "abc123", GitRef: "main", PipelineID: 7, Contexts: []string{"ci/build", "ci/test"}}
+	tests := []struct {
+		name        string
+		failing     string
+		wantFailed  []string
+		wantRemoved bool
+	}{
+		{name: "all finished", wantRemoved: true},
+		{name: "context failed", failing: "ci/test", wantFailed: []string{"ci/test"}, wantRemoved: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pth := filepath.Join(t.TempDir(), "state.json")
+			if err := saveState(pth, state); err != nil {
+				t.Fatal(err)
+			}
+
+			var sent []config
+			failed := finishContexts(config{Status: "success"}, state, pth, func(cfg config) error {
+				sent = append(sent, cfg)
+				if cfg.Context == tt.failing {
+					return errors.New("server error")
+				}
+				return nil
+			})
+
+			if !reflect.DeepEqual(failed, tt.wantFailed) {
+				t.Errorf("finishContexts() = %v, want %v", failed, tt.wantFailed)
+			}
+			if len(sent) != 2 || sent[0].CommitHash != "abc123" || sent[0].ProjectID != "group/app" || sent[0].PipelineID != 7 {
+				t.Errorf("finishContexts() sent %v, want the statuses of the saved state", sent)
+			}
+			if _, err := os.Stat(pth); os.IsNotExist(err) != tt.wantRemoved {
+				t.Errorf("state file removed = %v, want %v", os.IsNotExist(err), tt.wantRemoved)
+			}
+		})
+	}
+}
+
+func Test_isStateOf(t *testing.T) {
+	state := buildState{Project: "group/app", CommitHash: "abc123", GitRef: "main", Contexts: []string{"ci/build"}}
+	tests := []struct {
+		name string
+		cfg  config
+		want bool
+	}{
+		{"same build", config{ProjectID: "group/app", CommitHash: "abc123"}, true},
+		{"other commit", config{ProjectID: "group/app", CommitHash: "def456"}, false},
+		{"other project", config{ProjectID: "group/lib", CommitHash: "abc123"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isStateOf(state, tt.cfg); got != tt.want {
+				t.Errorf("isStateOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+const defaultLintMaxComments = 25
+
+// finding is a single issue reported by a lint or static analysis tool.
+type finding struct {
+	Tool     string
+	Path     string
+	Line     int
+	Severity string
+	Rule     string
+	Message  string
+}
+
+// checkstyleReport is the Checkstyle XML format, used by SwiftLint, detekt and golangci-lint among others.
+type checkstyleReport struct {
+	Files []struct {
+		Name   string `xml:"name,attr"`
+		Errors []struct {
+			Line     int    `xml:"line,attr"`
+			Severity string `xml:"severity,attr"`
+			Message  string `xml:"message,attr"`
+			Source   string `xml:"source,attr"`
+		} `xml:"error"`
+	} `xml:"file"`
+}
+
+// sarifReport is the subset of the SARIF 2.1.0 format used to report findings.
+type sarifReport struct {
+	Runs []struct {
+		Tool struct {
+			Driver struct {
+				Name string `json:"name"`
+			} `json:"driver"`
+		} `json:"tool"`
+		Results []struct {
+			RuleID  string `json:"ruleId"`
+			Level   string `json:"level"`
+			Message struct {
+				Text string `json:"text"`
+			} `json:"message"`
+			Locations []struct {
+				PhysicalLocation struct {
+					ArtifactLocation struct {
+						URI string `json:"uri"`
+					} `json:"artifactLocation"`
+					Region struct {
+						StartLine int `json:"startLine"`
+					} `json:"region"`
+				} `json:"physicalLocation"`
+			} `json:"locations"`
+		} `json:"results"`
+	} `json:"runs"`
+}
+
+func parseCheckstyle(data []byte) ([]finding, error) {
+	var report checkstyleReport
+	if err := xml.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+
+	var findings []finding
+	for _, f := range report.Files {
+		for _, e := range f.Errors {
+			findings = append(findings, finding{
+				Tool:     "checkstyle",
+				Path:     f.Name,
+				Line:     e.Line,
+				Severity: e.Severity,
+				Rule:     e.Source,
+				Message:  e.Message,
+			})
+		}
+	}
+	return findings, nil
+}
+
+func parseSARIF(data []byte) ([]finding, error) {
+	var report sarifReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+
+	var findings []finding
+	for _, run := range report.Runs {
+		for _, r := range run.Results {
+			for _, l := range r.Locations {
+				findings = append(findings, finding{
+					Tool:     run.Tool.Driver.Name,
+					Path:     strings.TrimPrefix(l.PhysicalLocation.ArtifactLocation.URI, "file://"),
+					Line:     l.PhysicalLocation.Region.StartLine,
+					Severity: r.Level,
+					Rule:     r.RuleID,
+					Message:  r.Message.Text,
+				})
+			}
+		}
+	}
+	return findings, nil
+}
+
+// parseLintReport parses a Checkstyle XML or SARIF report, the format is detected by the content.
+func parseLintReport(pth string) ([]finding, error) {
+	data, err := ioutil.ReadFile(pth)
+	if err != nil {
+		return nil, err
+	}
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "{") {
+		return parseSARIF(data)
+	}
+	return parseCheckstyle(data)
+}
+
+// mergeRequestDiff is a changed file of a merge request as returned by the GitLab API.
+// see also: https://docs.gitlab.com/ee/api/merge_requests.html#list-merge-request-diffs
+type mergeRequestDiff struct {
+	OldPath     string `json:"old_path"`
+	NewPath     string `json:"new_path"`
+	Diff        string `json:"diff"`
+	DeletedFile bool   `json:"deleted_file"`
+}
+
+// mergeRequestVersion is a diff version of a merge request, its refs are needed to position diff discussions.
+type mergeRequestVersion struct {
+	ID             int    `json:"id"`
+	HeadCommitSHA  string `json:"head_commit_sha"`
+	BaseCommitSHA  string `json:"base_commit_sha"`
+	StartCommitSHA string `json:"start_commit_sha"`
+}
+
+func listMergeRequestDiffs(cfg config, iid int) ([]mergeRequestDiff, error) {
+	var diffs []mergeRequestDiff
+	path := fmt.Sprintf("projects/%s/merge_requests/%d/diffs", projectPath(cfg), iid)
+	for page := 1; page <= maxPages; page++ {
+		query := url.Values{"per_page": {"100"}, "page": {strconv.Itoa(page)}}
+
+		var pageDiffs []mergeRequestDiff
+		if err := apiRequest(cfg, http.MethodGet, path, query, nil, "", &pageDiffs); err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, pageDiffs...)
+		if len(pageDiffs) < 100 {
+			break
+		}
+	}
+	return diffs, nil
+}
+
+func latestMergeRequestVersion(cfg config, iid int) (mergeRequestVersion, error) {
+	var versions []mergeRequestVersion
+	path := fmt.Sprintf("projects/%s/merge_requests/%d/versions", projectPath(cfg), iid)
+	if err := apiRequest(cfg, http.MethodGet, path, nil, nil, "", &versions); err != nil {
+		return mergeRequestVersion{}, err
+	}
+	if len(versions) == 0 {
+		return mergeRequestVersion{}, fmt.Errorf("merge request !%d has no diff versions", iid)
+	}
+	return versions[0], nil
+}
+
+var hunkRegexp = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// addedLines returns the line numbers added in the new version of the file by the unified diff.
+func addedLines(diff string) map[int]bool {
+	lines := map[int]bool{}
+	var line int
+	for _, l := range strings.Split(diff, "\n") {
+		if matches := hunkRegexp.FindStringSubmatch(l); len(matches) == 2 {
+			line, _ = strconv.Atoi(matches[1])
+			continue
+		}
+		if line == 0 {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(l, "+"):
+			lines[line] = true
+			line++
+		case strings.HasPrefix(l, "-"), strings.HasPrefix(l, "\\"):
+		default:
+			line++
+		}
+	}
+	return lines
+}
+
+// matchDiff returns the changed file the finding belongs to, report paths are often absolute
+// so they are matched to the repository relative diff paths by suffix.
+func matchDiff(diffs []mergeRequestDiff, pth string) (mergeRequestDiff, bool) {
+	pth = filepath.ToSlash(pth)
+	for _, d := range diffs {
+		if d.DeletedFile {
+			continue
+		}
+		if pth == d.NewPath || strings.HasSuffix(pth, "/"+d.NewPath) {
+			return d, true
+		}
+	}
+	return mergeRequestDiff{}, false
+}
+
+// findingsOnChangedLines keeps the findings on lines added by the merge request.
+func findingsOnChangedLines(findings []finding, diffs []mergeRequestDiff) map[finding]mergeRequestDiff {
+	changed := map[string]map[int]bool{}
+	result := map[finding]mergeRequestDiff{}
+	for _, f := range findings {
+		d, ok := matchDiff(diffs, f.Path)
+		if !ok {
+			continue
+		}
+		if _, ok := changed[d.NewPath]; !ok {
+			changed[d.NewPath] = addedLines(d.Diff)
+		}
+		if changed[d.NewPath][f.Line] {
+			result[f] = d
+		}
+	}
+	return result
+}
+
+// lintMarker identifies the discussion of a finding, so that it is not posted again by later builds.
+func lintMarker(d mergeRequestDiff, f finding) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s:%d:%s:%s", d.NewPath, f.Line, f.Rule, f.Message)))
+	return noteMarker("lint", fmt.Sprintf("%x", sum[:8]))
+}
+
+func lintBody(d mergeRequestDiff, f finding) string {
+	body := lintMarker(d, f) + "\n"
+	body += fmt.Sprintf(":warning: **%s**", f.Tool)
+	if f.Severity != "" {
+		body += fmt.Sprintf(" (%s)", f.Severity)
+	}
+	if f.Rule != "" {
+		body += fmt.Sprintf(" `%s`", f.Rule)
+	}
+	return body + fmt.Sprintf(": %s", f.Message)
+}
+
+// postLintDiscussions posts the findings of the lint reports on the lines changed by the merge request
+// as diff discussions. Findings already posted by earlier builds are skipped, at most cfg.LintMaxComments are posted.
+func postLintDiscussions(cfg config, mr *mergeRequest) error {
+	var findings []finding
+	for _, pattern := range cfg.LintReportPaths {
+		if pattern = strings.TrimSpace(pattern); pattern == "" {
+			continue
+		}
+		pths, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid lint report path (%s): %s", pattern, err)
+		}
+		for _, pth := range pths {
+			reportFindings, err := parseLintReport(pth)
+			if err != nil {
+				log.Warnf("Failed to parse lint report (%s), error: %s", pth, err)
+				continue
+			}
+			log.Printf("%d finding(s) in %s", len(reportFindings), pth)
+			findings = append(findings, reportFindings...)
+		}
+	}
+	if len(findings) == 0 {
+		return nil
+	}
+
+	diffs, err := listMergeRequestDiffs(cfg, mr.IID)
+	if err != nil {
+		return fmt.Errorf("failed to list merge request diffs: %s", err)
+	}
+	version, err := latestMergeRequestVersion(cfg, mr.IID)
+	if err != nil {
+		return fmt.Errorf("failed to get merge request version: %s", err)
+	}
+	discussions, err := listMergeRequestDiscussions(cfg, mr.IID)
+	if err != nil {
+		return fmt.Errorf("failed to list merge request discussions: %s", err)
+	}
+
+	maxComments := cfg.LintMaxComments
+	if maxComments <= 0 {
+		maxComments = defaultLintMaxComments
+	}
+
+	var posted, skipped int
+	changed := findingsOnChangedLines(findings, diffs)
+	for _, f := range findings {
+		d, ok := changed[f]
+		if !ok {
+			continue
+		}
+		if len(findDiscussions(discussions, lintMarker(d, f))) > 0 {
+			skipped++
+			continue
+		}
+		if posted >= maxComments {
+			log.Warnf("Reached the limit of %d lint comments, the remaining findings are not posted", maxComments)
+			break
+		}
+
+		form := url.Values{
+			"body":                    {lintBody(d, f)},
+			"position[position_type]": {"text"},
+			"position[base_sha]":      {version.BaseCommitSHA},
+			"position[start_sha]":     {version.StartCommitSHA},
+			"position[head_sha]":      {version.HeadCommitSHA},
+			"position[old_path]":      {d.OldPath},
+			"position[new_path]":      {d.NewPath},
+			"position[new_line]":      {strconv.Itoa(f.Line)},
+		}
+		created, err := createMergeRequestDiscussion(cfg, mr.IID, form)
+		if err != nil {
+			log.Warnf("Failed to comment on %s:%d, error: %s", d.NewPath, f.Line, err)
+			continue
+		}
+		discussions = append(discussions, created)
+		posted++
+	}
+
+	log.Donef("Posted %d lint comment(s) on !%d, %d already posted, %d finding(s) on changed lines", posted, mr.IID, skipped, len(changed))
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parseLintReports(t *testing.T) {
+	checkstyle := `<?xml version="1.0" encoding="utf-8"?>
+<checkstyle version="4.3">
+<file name="/bitrise/src/App/View.swift">
+<error line="12" column="5" severity="warning" message="Line should be 120 characters or less" source="swiftlint.rules.line_length"/>
+</file>
+</checkstyle>`
+	got, err := parseCheckstyle([]byte(checkstyle))
+	if err != nil {
+		t.Fatalf("parseCheckstyle() error = %v", err)
+	}
+	want := []finding{{Tool: "checkstyle", Path: "/bitrise/src/App/View.swift", Line: 12, Severity: "warning", Rule: "swiftlint.rules.line_length", Message: "Line should be 120 characters or less"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseCheckstyle() = %v, want %v", got, want)
+	}
+
+	sarif := `{"version": "2.1.0", "runs": [{"tool": {"driver": {"name": "detekt"}}, "results": [
+{"ruleId": "MagicNumber", "level": "warning", "message": {"text": "This expression contains a magic number."},
+"locations": [{"physicalLocation": {"artifactLocation": {"uri": "file:///bitrise/src/app/Main.kt"}, "region": {"startLine": 7}}}]}]}]}`
+	got, err = parseSARIF([]byte(sarif))
+	if err != nil {
+		t.Fatalf("parseSARIF() error = %v", err)
+	}
+	want = []finding{{Tool: "detekt", Path: "/bitrise/src/app/Main.kt", Line: 7, Severity: "warning", Rule: "MagicNumber", Message: "This expression contains a magic number."}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseSARIF() = %v, want %v", got, want)
+	}
+}
+
+func Test_findingsOnChangedLines(t *testing.T) {
+	diffs := []mergeRequestDiff{{
+		OldPath: "App/View.swift",
+		NewPath: "App/View.swift",
+		Diff:    "@@ -10,4 +10,5 @@ struct View {\n context\n-removed\n+added 11\n+added 12\n context\n\\ No newline at end of file\n",
+	}}
+	findings := []finding{
+		{Path: "/bitrise/src/App/View.swift", Line: 11},
+		{Path: "/bitrise/src/App/View.swift", Line: 12},
+		{Path: "/bitrise/src/App/View.swift", Line: 13},
+		{Path: "/bitrise/src/App/Other.swift", Line: 11},
+	}
+
+	got := findingsOnChangedLines(findings, diffs)
+	if len(got) != 2 {
+		t.Fatalf("findingsOnChangedLines() = %v, want 2 findings", got)
+	}
+	for _, f := range findings[:2] {
+		if _, ok := got[f]; !ok {
+			t.Errorf("findingsOnChangedLines() is missing %v", f)
+		}
+	}
+}
@@ -1,12 +1,13 @@
 package main
 
 import (
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -22,11 +23,94 @@ type config struct {
 	CommitHash    string `env:"commit_hash,required"`
 	APIURL        string `env:"api_base_url,required"`
 
-	Status      string  `env:"preset_status,opt[auto,pending,running,success,failed,canceled]"`
-	TargetURL   string  `env:"target_url"`
-	Context     string  `env:"context"`
-	Description string  `env:"description"`
-	Coverage    float64 `env:"coverage,range[0.0..100.0]"`
+	Status      string   `env:"preset_status,opt[auto,pending,running,success,failed,canceled]"`
+	TargetURL   string   `env:"target_url"`
+	Context     string   `env:"context"`
+	Description string   `env:"description"`
+	Coverage    *float64 `env:"coverage,range[0.0..100.0]"`
+
+	SkipOutdated bool `env:"skip_outdated,opt[yes,no]"`
+	BuildNumber  int  `env:"build_number"`
+
+	Mode       string `env:"mode,opt[status,start,finish,janitor,aggregate,wait_for_statuses,release,deployment]"`
+	StatePath  string `env:"state_path"`
+	ProjectID  string `env:"project_id"`
+	PipelineID int    `env:"pipeline_id"`
+
+	JanitorRefs        []string `env:"janitor_refs"`
+	JanitorContexts    []string `env:"janitor_contexts"`
+	JanitorMaxAge      string   `env:"janitor_max_age"`
+	JanitorCommitLimit int      `env:"janitor_commit_limit"`
+	DryRun             bool     `env:"dry_run,opt[yes,no]"`
+
+	CancelSuperseded bool `env:"cancel_superseded,opt[yes,no]"`
+
+	AggregatePrefix string `env:"aggregate_context_prefix"`
+	AggregateRegex  string `env:"aggregate_context_regex"`
+	AggregateWait   bool   `env:"aggregate_wait,opt[yes,no]"`
+	WaitTimeout     string `env:"wait_timeout"`
+	PollInterval    string `env:"poll_interval"`
+
+	WaitContexts    []string `env:"wait_contexts"`
+	WaitForPipeline bool     `env:"wait_for_pipeline,opt[yes,no]"`
+
+	MergeRequestIID   string `env:"merge_request_iid"`
+	PostToMergeCommit bool   `env:"post_to_merge_commit,opt[yes,no]"`
+
+	SummaryNote            bool     `env:"summary_note,opt[yes,no]"`
+	DeleteSummaryOnSuccess bool     `env:"delete_summary_on_success,opt[yes,no]"`
+	ArtifactURLs           []string `env:"artifact_urls"`
+
+	FailureDiscussion bool   `env:"failure_discussion,opt[yes,no]"`
+	FailedStep        string `env:"failed_step"`
+	FailureLogPath    string `env:"failure_log_path"`
+	FailureLogLines   int    `env:"failure_log_lines"`
+
+	LintReportPaths []string `env:"lint_report_paths"`
+	LintMaxComments int      `env:"lint_max_comments"`
+
+	CoverageReportPaths []string `env:"coverage_report_paths"`
+
+	CoverageCompare        bool     `env:"coverage_compare,opt[yes,no]"`
+	CoverageMin            *float64 `env:"coverage_min,range[0.0..100.0]"`
+	CoverageMaxDrop        *float64 `env:"coverage_max_drop,range[0.0..100.0]"`
+	CoverageGateFailsBuild bool     `env:"coverage_gate_fails_build,opt[yes,no]"`
+
+	TestResultsSummary bool   `env:"test_results_summary,opt[yes,no]"`
+	TestResultsDir     string `env:"test_results_dir"`
+
+	AttachmentPaths     []string `env:"attachment_paths"`
+	AttachmentMaxSizeMB int      `env:"attachment_max_size_mb"`
+
+	PackagePaths     []string `env:"package_paths"`
+	PackageName      string   `env:"package_name"`
+	PackageVersion   string   `env:"package_version"`
+	PackageMaxSizeMB int      `env:"package_max_size_mb"`
+	PackageTargetURL bool     `env:"package_target_url,opt[yes,no]"`
+
+	ReleaseTag           string   `env:"release_tag"`
+	ReleaseName          string   `env:"release_name"`
+	ReleaseNotesPath     string   `env:"release_notes_path"`
+	ReleaseNotesTemplate string   `env:"release_notes_template"`
+	ReleaseLinks         []string `env:"release_links"`
+
+	EnvironmentName string `env:"environment_name"`
+	EnvironmentURL  string `env:"environment_url"`
+	DeploymentID    string `env:"deployment_id"`
+
+	ExternalStatusCheck string `env:"external_status_check"`
+	ApproveMergeRequest bool   `env:"approve_merge_request,opt[yes,no]"`
+
+	StateLabels []string `env:"state_labels"`
+
+	AutoMergeLabel      string `env:"auto_merge_label"`
+	AutoMergeFailsBuild bool   `env:"auto_merge_fails_build,opt[yes,no]"`
+
+	TrackingIssue       bool     `env:"tracking_issue,opt[yes,no]"`
+	TrackingIssueLabels []string `env:"tracking_issue_labels"`
+
+	CommentReferencedIssues bool   `env:"comment_referenced_issues,opt[yes,no]"`
+	CommitMessage           string `env:"commit_message"`
 }
 
 // getRepo parses the repository from a url
@@ -55,48 +139,173 @@ func getDescription(desc, state string) string {
 	return desc
 }
 
-// sendStatus creates a commit status for the given commit.
-// see also: https://docs.gitlab.com/ce/api/commits.html#post-the-build-status-to-a-commit
-func sendStatus(cfg config) error {
-	repo := url.PathEscape(getRepo(cfg.RepositoryURL))
+func getStatusDescription(cfg config) string {
+	desc := getDescription(cfg.Description, cfg.Status)
+	if cfg.SkipOutdated {
+		return withBuildNumber(desc, cfg.BuildNumber)
+	}
+	return desc
+}
+
+// statusForm returns the parameters of the commit status, the coverage is only sent if it was set or computed.
+func statusForm(cfg config) url.Values {
 	form := url.Values{
 		"state":       {getState(cfg.Status)},
 		"target_url":  {cfg.TargetURL},
-		"description": {getDescription(cfg.Description, cfg.Status)},
+		"description": {getStatusDescription(cfg)},
 		"context":     {cfg.Context},
-		"coverage":    {fmt.Sprintf("%f", cfg.Coverage)},
 	}
 
+	if cfg.Coverage != nil {
+		form["coverage"] = []string{fmt.Sprintf("%f", *cfg.Coverage)}
+	}
 	if strings.TrimSpace(cfg.GitRef) != "" {
 		form["ref"] = []string{strings.TrimSpace(cfg.GitRef)}
 	}
+	if cfg.PipelineID > 0 {
+		form["pipeline_id"] = []string{strconv.Itoa(cfg.PipelineID)}
+	}
+	return form
+}
 
-	url := fmt.Sprintf("%s/projects/%s/statuses/%s", cfg.APIURL, repo, cfg.CommitHash)
-	req, err := http.NewRequest("POST", url, strings.NewReader(form.Encode()))
-	if err != nil {
+// sendStatus creates a commit status for the given commit.
+// see also: https://docs.gitlab.com/ce/api/commits.html#post-the-build-status-to-a-commit
+func sendStatus(cfg config) (commitStatus, error) {
+	state := getState(cfg.Status)
+	form := statusForm(cfg)
+
+	var status commitStatus
+	path := fmt.Sprintf("projects/%s/statuses/%s", projectPath(cfg), cfg.CommitHash)
+	err := apiRequest(cfg, http.MethodPost, path, nil, strings.NewReader(form.Encode()), "application/x-www-form-urlencoded", &status)
+	return status, checkTransition(err, state)
+}
+
+// sendStatusWithRetry sends the status, retrying on failures except for rejected state transitions.
+func sendStatusWithRetry(cfg config) (commitStatus, error) {
+	var status commitStatus
+	var transitionErr *transitionError
+	if err := retry.Times(3).Wait(5 * time.Second).Try(func(attempt uint) error {
+		if attempt > 0 {
+			log.Warnf("%d attempt failed", attempt)
+		}
+
+		var err error
+		status, err = sendStatus(cfg)
+		if errors.As(err, &transitionErr) {
+			return nil
+		}
 		return err
+	}); err != nil {
+		return commitStatus{}, err
+	}
+
+	if transitionErr != nil {
+		return commitStatus{}, transitionErr
+	}
+	return status, nil
+}
+
+// reportStatus reports the build status of the configured commit, or the head of the merge request.
+func reportStatus(cfg config, mr *mergeRequest) error {
+	targets := statusTargets(cfg, mr)
+	for _, target := range targets {
+		if target.SkipOutdated {
+			outdated, err := isOutdated(target)
+			if err != nil {
+				log.Warnf("Failed to check statuses of newer builds, error: %s", err)
+			} else if outdated {
+				continue
+			}
+		}
+
+		if _, err := sendMergeRequestStatus(target, mr); err != nil {
+			return err
+		}
+	}
+
+	if cfg.CancelSuperseded {
+		if err := cancelSupersededStatuses(targets[0]); err != nil {
+			log.Warnf("Failed to cancel statuses of superseded commits, error: %s", err)
+		}
 	}
-	req.Header.Add("PRIVATE-TOKEN", cfg.PrivateToken)
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	return nil
+}
 
-	resp, err := http.DefaultClient.Do(req)
+func run(cfg config) error {
+	switch cfg.Mode {
+	case "janitor":
+		return cleanupStaleStatuses(cfg)
+	case "release":
+		return createRelease(cfg)
+	case "deployment":
+		return reportDeployment(cfg)
+	}
+
+	mr, err := detectMergeRequest(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to send the request: %s", err)
+		log.Warnf("%s, reporting status to the checked out commit", err)
+	} else if mr != nil {
+		exportMergeRequest(mr)
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
+	// The statuses of merge request builds are reported to the head of the merge request,
+	// so the child statuses are looked up there too, not on the checked out merge commit.
+	switch cfg.Mode {
+	case "aggregate":
+		return aggregateStatuses(statusTargets(cfg, mr)[0])
+	case "wait_for_statuses":
+		target := statusTargets(cfg, mr)[0]
+		if mr != nil {
+			// Merge request pipelines run for refs/merge-requests/<iid>/head instead of the source branch,
+			// the head commit identifies the statuses and pipelines of the merge request.
+			target.GitRef = ""
+		}
+		return waitForStatuses(target)
+	}
+
+	cfg, report := prepareReport(cfg, mr)
+
+	state := getState(cfg.Status)
+	switch cfg.Mode {
+	case "start":
+		state = "running"
+		err = startBuild(cfg, mr)
+	case "finish":
+		err = finishBuild(cfg, mr)
+	default:
+		err = reportStatus(cfg, mr)
+	}
 	if err != nil {
 		return err
 	}
 
-	if err := resp.Body.Close(); err != nil {
-		return err
+	publishReport(cfg, mr, state, report)
+
+	if mr != nil && cfg.AutoMergeLabel != "" && state == "success" {
+		if err := mergeWhenPipelineSucceeds(cfg, mr); err != nil {
+			if cfg.AutoMergeFailsBuild {
+				return err
+			}
+			log.Warnf("%s", err)
+		}
 	}
-	if 200 > resp.StatusCode || resp.StatusCode >= 300 {
-		return fmt.Errorf("server error: %s url: %s code: %d body: %s", resp.Status, url, resp.StatusCode, string(body))
+
+	if mr == nil && cfg.TrackingIssue && !isUnfinished(state) {
+		if err := updateTrackingIssue(cfg, state); err != nil {
+			log.Warnf("%s", err)
+		}
 	}
 
-	return err
+	if cfg.CommentReferencedIssues && !isUnfinished(state) {
+		if err := commentReferencedIssues(cfg, state); err != nil {
+			log.Warnf("%s", err)
+		}
+	}
+
+	if report.coverageErr != nil && cfg.CoverageGateFailsBuild {
+		return report.coverageErr
+	}
+	return nil
 }
 
 func main() {
@@ -112,13 +321,7 @@ func main() {
 	}
 	stepconf.Print(cfg)
 
-	if err := retry.Times(3).Wait(5 * time.Second).Try(func(attempt uint) error {
-		if attempt > 0 {
-			log.Warnf("%d attempt failed", attempt)
-		}
-
-		return sendStatus(cfg)
-	}); err != nil {
+	if err := run(cfg); err != nil {
 		log.Errorf("Failed to set status, error: %s", err)
 		os.Exit(1)
 	}
@@ -39,3 +39,27 @@ func Test_getRepo(t *testing.T) {
 		})
 	}
 }
+
+func Test_statusForm(t *testing.T) {
+	zero, covered := 0.0, 81.25
+	tests := []struct {
+		name     string
+		coverage *float64
+		want     string
+	}{
+		{"no coverage", nil, ""},
+		{"zero coverage", &zero, "0.000000"},
+		{"coverage", &covered, "81.250000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := statusForm(config{Status: "success", Context: "Bitrise", Coverage: tt.coverage})
+			if got := form.Get("coverage"); got != tt.want {
+				t.Errorf("statusForm() coverage = %q, want %q", got, tt.want)
+			}
+			if _, ok := form["coverage"]; ok != (tt.coverage != nil) {
+				t.Errorf("statusForm() has coverage = %v, want %v", ok, tt.coverage != nil)
+			}
+		})
+	}
+}
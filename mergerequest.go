@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// user is a GitLab user as embedded in other API responses.
+type user struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+}
+
+// mergeRequest is a merge request as returned by the GitLab API.
+// see also: https://docs.gitlab.com/ee/api/merge_requests.html#get-single-mr
+type mergeRequest struct {
+	ID              int      `json:"id"`
+	IID             int      `json:"iid"`
+	ProjectID       int      `json:"project_id"`
+	Title           string   `json:"title"`
+	State           string   `json:"state"`
+	SourceBranch    string   `json:"source_branch"`
+	TargetBranch    string   `json:"target_branch"`
+	SourceProjectID int      `json:"source_project_id"`
+	TargetProjectID int      `json:"target_project_id"`
+	SHA             string   `json:"sha"`
+	MergeCommitSHA  string   `json:"merge_commit_sha"`
+	WebURL          string   `json:"web_url"`
+	Labels          []string `json:"labels"`
+	Author          user     `json:"author"`
+	DiffRefs        struct {
+		BaseSHA  string `json:"base_sha"`
+		HeadSHA  string `json:"head_sha"`
+		StartSHA string `json:"start_sha"`
+	} `json:"diff_refs"`
+
+	DetailedMergeStatus         string `json:"detailed_merge_status"`
+	HasConflicts                bool   `json:"has_conflicts"`
+	BlockingDiscussionsResolved bool   `json:"blocking_discussions_resolved"`
+	MergeWhenPipelineSucceeds   bool   `json:"merge_when_pipeline_succeeds"`
+}
+
+func getMergeRequest(cfg config, iid string) (*mergeRequest, error) {
+	var mr mergeRequest
+	path := fmt.Sprintf("projects/%s/merge_requests/%s", projectPath(cfg), iid)
+	if err := apiRequest(cfg, http.MethodGet, path, nil, nil, "", &mr); err != nil {
+		return nil, err
+	}
+	return &mr, nil
+}
+
+// detectMergeRequest fetches the merge request of the build, it returns nil if the build is not a merge request build.
+func detectMergeRequest(cfg config) (*mergeRequest, error) {
+	iid := strings.TrimPrefix(strings.TrimSpace(cfg.MergeRequestIID), "!")
+	if iid == "" {
+		return nil, nil
+	}
+
+	mr, err := getMergeRequest(cfg, iid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch merge request !%s: %s", iid, err)
+	}
+	log.Infof("Merge request !%d: %s (%s → %s), head: %s", mr.IID, mr.Title, mr.SourceBranch, mr.TargetBranch, shortSHA(mr.SHA))
+	return mr, nil
+}
+
+// statusTargets returns the configs of the commits the status is reported to:
+// the head of the merge request, and the checked out merge commit too if requested.
+// The head of a merge request from a fork is reported to the source project, as the commit belongs to the fork.
+func statusTargets(cfg config, mr *mergeRequest) []config {
+	if mr == nil || mr.SHA == "" {
+		return []config{cfg}
+	}
+
+	head := cfg
+	head.CommitHash = mr.SHA
+	head.GitRef = mr.SourceBranch
+	if isFork(mr) {
+		log.Infof("Merge request from fork project %d, reporting the status to the fork", mr.SourceProjectID)
+		head.ProjectID = strconv.Itoa(mr.SourceProjectID)
+	}
+	targets := []config{head}
+
+	if cfg.PostToMergeCommit && cfg.CommitHash != "" && !strings.HasPrefix(mr.SHA, cfg.CommitHash) {
+		targets = append(targets, cfg)
+	}
+	return targets
+}
+
+// reportToMergeRequest updates the merge request with the outcome of the build,
+// failures are logged as warnings as the status is already reported.
+// The sections are added to the summary comment.
+func reportToMergeRequest(cfg config, mr *mergeRequest, state string, sections []string) {
+	cfg.CommitHash = mr.SHA
+
+	if cfg.SummaryNote {
+		if err := upsertSummaryNote(cfg, mr, state, sections); err != nil {
+			log.Warnf("%s", err)
+		}
+	}
+
+	if cfg.FailureDiscussion {
+		if err := updateFailureDiscussion(cfg, mr, state); err != nil {
+			log.Warnf("%s", err)
+		}
+	}
+
+	if len(cfg.LintReportPaths) > 0 && !isUnfinished(state) {
+		if err := postLintDiscussions(cfg, mr); err != nil {
+			log.Warnf("%s", err)
+		}
+	}
+
+	if cfg.ExternalStatusCheck != "" {
+		if err := respondToStatusCheck(cfg, mr, state); err != nil {
+			log.Warnf("%s", err)
+		}
+	}
+
+	if cfg.ApproveMergeRequest {
+		if err := updateApproval(cfg, mr, state); err != nil {
+			log.Warnf("%s", err)
+		}
+	}
+
+	if len(cfg.StateLabels) > 0 {
+		if err := updateStateLabels(cfg, mr, state); err != nil {
+			log.Warnf("%s", err)
+		}
+	}
+}
+
+// exportEnv exports an output environment variable with envman.
+func exportEnv(key, value string) error {
+	cmd := exec.Command("envman", "add", "--key", key, "--value", value)
+	cmd.Stderr = os.Stderr
+	if out, err := cmd.Output(); err != nil {
+		return fmt.Errorf("failed to export %s: %s %s", key, err, string(out))
+	}
+	return nil
+}
+
+// exportMergeRequest exports the merge request's metadata as output environment variables.
+func exportMergeRequest(mr *mergeRequest) {
+	outputs := []struct{ key, value string }{
+		{"GITLAB_MR_IID", fmt.Sprintf("%d", mr.IID)},
+		{"GITLAB_MR_TITLE", mr.Title},
+		{"GITLAB_MR_AUTHOR", mr.Author.Username},
+		{"GITLAB_MR_SOURCE_BRANCH", mr.SourceBranch},
+		{"GITLAB_MR_TARGET_BRANCH", mr.TargetBranch},
+		{"GITLAB_MR_LABELS", strings.Join(mr.Labels, ",")},
+		{"GITLAB_MR_HEAD_SHA", mr.SHA},
+		{"GITLAB_MR_WEB_URL", mr.WebURL},
+	}
+	for _, o := range outputs {
+		if err := exportEnv(o.key, o.value); err != nil {
+			log.Warnf("%s", err)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func Test_statusTargets(t *testing.T) {
+	mr := &mergeRequest{SHA: "1111111111111111111111111111111111111111", SourceBranch: "feature"}
+	tests := []struct {
+		name       string
+		cfg        config
+		mr         *mergeRequest
+		wantHashes []string
+	}{
+		{"no merge request", config{CommitHash: "2222222222222222222222222222222222222222"}, nil, []string{"2222222222222222222222222222222222222222"}},
+		{"merge request head", config{CommitHash: "2222222222222222222222222222222222222222"}, mr, []string{mr.SHA}},
+		{"merge request head and merge commit", config{CommitHash: "2222222222222222222222222222222222222222", PostToMergeCommit: true}, mr, []string{mr.SHA, "2222222222222222222222222222222222222222"}},
+		{"merge commit is the head", config{CommitHash: mr.SHA, PostToMergeCommit: true}, mr, []string{mr.SHA}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets := statusTargets(tt.cfg, tt.mr)
+			if len(targets) != len(tt.wantHashes) {
+				t.Fatalf("statusTargets() = %d targets, want %d", len(targets), len(tt.wantHashes))
+			}
+			for i, target := range targets {
+				if target.CommitHash != tt.wantHashes[i] {
+					t.Errorf("statusTargets()[%d].CommitHash = %s, want %s", i, target.CommitHash, tt.wantHashes[i])
+				}
+			}
+		})
+	}
+}
+
+func Test_statusTargets_fork(t *testing.T) {
+	mr := &mergeRequest{IID: 7, SHA: "1111111111111111111111111111111111111111", SourceBranch: "feature", SourceProjectID: 12, TargetProjectID: 34}
+	cfg := config{CommitHash: "2222222222222222222222222222222222222222", PostToMergeCommit: true}
+
+	targets := statusTargets(cfg, mr)
+	if len(targets) != 2 {
+		t.Fatalf("statusTargets() = %d targets, want 2", len(targets))
+	}
+	if targets[0].ProjectID != "12" || !isForkTarget(targets[0], mr) {
+		t.Errorf("statusTargets()[0].ProjectID = %s, want the source project 12", targets[0].ProjectID)
+	}
+	if targets[1].ProjectID != "" || isForkTarget(targets[1], mr) {
+		t.Errorf("statusTargets()[1].ProjectID = %s, want the configured project", targets[1].ProjectID)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// maxPages limits the number of pages fetched from paginated endpoints.
+const maxPages = 20
+
+// noteMarker identifies the comment, discussion or issue of the given kind and key posted by the Step,
+// so later builds can find and update it. The marker is an HTML comment, so it is not rendered by GitLab.
+func noteMarker(kind, key string) string {
+	return fmt.Sprintf("<!-- bitrise-gitlab-status:%s:%s -->", kind, key)
+}
+
+// note is a merge request or issue comment as returned by the GitLab API.
+// see also: https://docs.gitlab.com/ee/api/notes.html
+type note struct {
+	ID         int       `json:"id"`
+	Body       string    `json:"body"`
+	Author     user      `json:"author"`
+	System     bool      `json:"system"`
+	Resolvable bool      `json:"resolvable"`
+	Resolved   bool      `json:"resolved"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// createMergeRequestNote adds a comment to the merge request.
+func createMergeRequestNote(cfg config, iid int, body string) (note, error) {
+	form := url.Values{"body": {body}}
+
+	var n note
+	path := fmt.Sprintf("projects/%s/merge_requests/%d/notes", projectPath(cfg), iid)
+	err := apiRequest(cfg, http.MethodPost, path, nil, strings.NewReader(form.Encode()), "application/x-www-form-urlencoded", &n)
+	return n, err
+}
+
+// listMergeRequestNotes returns the comments of the merge request, the newest first.
+func listMergeRequestNotes(cfg config, iid int) ([]note, error) {
+	var notes []note
+	path := fmt.Sprintf("projects/%s/merge_requests/%d/notes", projectPath(cfg), iid)
+	for page := 1; page <= maxPages; page++ {
+		query := url.Values{"sort": {"desc"}, "order_by": {"created_at"}, "per_page": {"100"}, "page": {strconv.Itoa(page)}}
+
+		var pageNotes []note
+		if err := apiRequest(cfg, http.MethodGet, path, query, nil, "", &pageNotes); err != nil {
+			return nil, err
+		}
+		notes = append(notes, pageNotes...)
+		if len(pageNotes) < 100 {
+			break
+		}
+	}
+	return notes, nil
+}
+
+// updateMergeRequestNote replaces the body of a merge request comment.
+func updateMergeRequestNote(cfg config, iid, noteID int, body string) error {
+	form := url.Values{"body": {body}}
+	path := fmt.Sprintf("projects/%s/merge_requests/%d/notes/%d", projectPath(cfg), iid, noteID)
+	return apiRequest(cfg, http.MethodPut, path, nil, strings.NewReader(form.Encode()), "application/x-www-form-urlencoded", nil)
+}
+
+// deleteMergeRequestNote deletes a merge request comment.
+func deleteMergeRequestNote(cfg config, iid, noteID int) error {
+	path := fmt.Sprintf("projects/%s/merge_requests/%d/notes/%d", projectPath(cfg), iid, noteID)
+	return apiRequest(cfg, http.MethodDelete, path, nil, nil, "", nil)
+}
+
+// findNote returns the first note containing the marker.
+func findNote(notes []note, marker string) (note, bool) {
+	for _, n := range notes {
+		if !n.System && strings.Contains(n.Body, marker) {
+			return n, true
+		}
+	}
+	return note{}, false
+}
+
+// upsertMarkedNote keeps a single comment marked with the marker up to date on the merge request,
+// so later statuses and builds do not add new comments. The comment is only created if create is set,
+// an empty body deletes the existing comment.
+func upsertMarkedNote(cfg config, mr *mergeRequest, marker, body string, create bool) error {
+	notes, err := listMergeRequestNotes(cfg, mr.IID)
+	if err != nil {
+		return fmt.Errorf("failed to list merge request comments: %s", err)
+	}
+	existing, found := findNote(notes, marker)
+
+	switch {
+	case found && body == "":
+		if err := deleteMergeRequestNote(cfg, mr.IID, existing.ID); err != nil {
+			return fmt.Errorf("failed to delete comment of merge request !%d: %s", mr.IID, err)
+		}
+		log.Donef("Deleted comment of !%d", mr.IID)
+	case found:
+		if err := updateMergeRequestNote(cfg, mr.IID, existing.ID, body); err != nil {
+			return fmt.Errorf("failed to update comment of merge request !%d: %s", mr.IID, err)
+		}
+		log.Donef("Updated comment of !%d", mr.IID)
+	case create && body != "":
+		if _, err := createMergeRequestNote(cfg, mr.IID, body); err != nil {
+			return fmt.Errorf("failed to comment on merge request !%d: %s", mr.IID, err)
+		}
+		log.Donef("Commented on !%d", mr.IID)
+	}
+	return nil
+}
+
+// discussion is a merge request discussion thread as returned by the GitLab API.
+// see also: https://docs.gitlab.com/ee/api/discussions.html#merge-requests
+type discussion struct {
+	ID    string `json:"id"`
+	Notes []note `json:"notes"`
+}
+
+// isResolved returns true if every resolvable note of the discussion is resolved.
+func (d discussion) isResolved() bool {
+	for _, n := range d.Notes {
+		if n.Resolvable && !n.Resolved {
+			return false
+		}
+	}
+	return true
+}
+
+// listMergeRequestDiscussions returns the discussions of the merge request.
+func listMergeRequestDiscussions(cfg config, iid int) ([]discussion, error) {
+	var discussions []discussion
+	path := fmt.Sprintf("projects/%s/merge_requests/%d/discussions", projectPath(cfg), iid)
+	for page := 1; page <= maxPages; page++ {
+		query := url.Values{"per_page": {"100"}, "page": {strconv.Itoa(page)}}
+
+		var pageDiscussions []discussion
+		if err := apiRequest(cfg, http.MethodGet, path, query, nil, "", &pageDiscussions); err != nil {
+			return nil, err
+		}
+		discussions = append(discussions, pageDiscussions...)
+		if len(pageDiscussions) < 100 {
+			break
+		}
+	}
+	return discussions, nil
+}
+
+// createMergeRequestDiscussion starts a new resolvable discussion on the merge request.
+func createMergeRequestDiscussion(cfg config, iid int, form url.Values) (discussion, error) {
+	var d discussion
+	path := fmt.Sprintf("projects/%s/merge_requests/%d/discussions", projectPath(cfg), iid)
+	err := apiRequest(cfg, http.MethodPost, path, nil, strings.NewReader(form.Encode()), "application/x-www-form-urlencoded", &d)
+	return d, err
+}
+
+// replyToDiscussion adds a note to the discussion.
+func replyToDiscussion(cfg config, iid int, discussionID, body string) error {
+	form := url.Values{"body": {body}}
+	path := fmt.Sprintf("projects/%s/merge_requests/%d/discussions/%s/notes", projectPath(cfg), iid, discussionID)
+	return apiRequest(cfg, http.MethodPost, path, nil, strings.NewReader(form.Encode()), "application/x-www-form-urlencoded", nil)
+}
+
+// resolveDiscussion marks the discussion as resolved.
+func resolveDiscussion(cfg config, iid int, discussionID string) error {
+	query := url.Values{"resolved": {"true"}}
+	path := fmt.Sprintf("projects/%s/merge_requests/%d/discussions/%s", projectPath(cfg), iid, discussionID)
+	return apiRequest(cfg, http.MethodPut, path, query, nil, "", nil)
+}
+
+// findDiscussions returns the discussions whose first note contains the marker.
+func findDiscussions(discussions []discussion, marker string) []discussion {
+	var found []discussion
+	for _, d := range discussions {
+		if len(d.Notes) > 0 && strings.Contains(d.Notes[0].Body, marker) {
+			found = append(found, d)
+		}
+	}
+	return found
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_upsertMarkedNote(t *testing.T) {
+	const marker = "<!-- bitrise-gitlab-status:summary:ci/build -->"
+	tests := []struct {
+		name   string
+		notes  []note
+		body   string
+		create bool
+		want   string
+	}{
+		{"create", nil, marker + "\nfailed", true, "POST /notes"},
+		{"not created", nil, marker + "\npassed", false, ""},
+		{"update", []note{{ID: 7, Body: marker + "\nfailed"}}, marker + "\npassed", false, "PUT /notes/7"},
+		{"delete", []note{{ID: 7, Body: marker + "\nfailed"}}, "", true, "DELETE /notes/7"},
+		{"nothing to delete", nil, "", true, ""},
+		{"system note ignored", []note{{ID: 7, Body: marker, System: true}}, marker + "\nfailed", true, "POST /notes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				const prefix = "/projects/group%2Fapp/merge_requests/3"
+				if r.Method == http.MethodGet {
+					if err := json.NewEncoder(w).Encode(tt.notes); err != nil {
+						t.Error(err)
+					}
+					return
+				}
+				got = r.Method + " " + r.URL.EscapedPath()[len(prefix):]
+				if _, err := w.Write([]byte("{}")); err != nil {
+					t.Error(err)
+				}
+			}))
+			defer server.Close()
+
+			cfg := config{APIURL: server.URL, ProjectID: "group/app"}
+			if err := upsertMarkedNote(cfg, &mergeRequest{IID: 3}, marker, tt.body, tt.create); err != nil {
+				t.Fatalf("upsertMarkedNote() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("upsertMarkedNote() sent %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
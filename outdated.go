@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// buildNumberRegexp matches the build number tag appended to the status description by withBuildNumber,
+// it is anchored to the end of the description, so issue references like "Fixes #123" are not taken as build numbers.
+var buildNumberRegexp = regexp.MustCompile(`\(build #(\d+)\)$`)
+
+// getContext returns the status context, GitLab falls back to "default" if it is not set.
+func getContext(context string) string {
+	if context == "" {
+		return "default"
+	}
+	return context
+}
+
+// withBuildNumber appends the build number to the description, so that later builds can tell which build reported the status.
+func withBuildNumber(desc string, buildNumber int) string {
+	if buildNumber <= 0 || buildNumberRegexp.MatchString(desc) {
+		return desc
+	}
+	return fmt.Sprintf("%s (build #%d)", desc, buildNumber)
+}
+
+// statusBuildNumber parses the build number tag of the status description.
+func statusBuildNumber(s commitStatus) (int, bool) {
+	matches := buildNumberRegexp.FindStringSubmatch(s.Description)
+	if len(matches) != 2 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(matches[1])
+	return n, err == nil
+}
+
+// findNewerStatus returns the first status which was reported by a newer build than the current one.
+// Statuses are compared by build number only: a status created after the current build was triggered
+// can still be reported by an older build which finished later, so statuses without a build number are never newer.
+func findNewerStatus(statuses []commitStatus, buildNumber int) (commitStatus, bool) {
+	if buildNumber <= 0 {
+		return commitStatus{}, false
+	}
+	for _, s := range statuses {
+		if n, ok := statusBuildNumber(s); ok && n > buildNumber {
+			return s, true
+		}
+	}
+	return commitStatus{}, false
+}
+
+// isOutdated checks whether a newer build already reported a status for the same commit and context.
+func isOutdated(cfg config) (bool, error) {
+	statuses, err := listStatuses(cfg, cfg.CommitHash, "", getContext(cfg.Context))
+	if err != nil {
+		return false, err
+	}
+
+	s, ok := findNewerStatus(statuses, cfg.BuildNumber)
+	if !ok {
+		log.Printf("No newer build reported status for context %s, updating status", getContext(cfg.Context))
+		return false, nil
+	}
+
+	log.Warnf("A newer build already reported %s status for context %s (%s, %s), skipping update", s.Status, s.Name, s.Description, s.CreatedAt.Format(time.RFC3339))
+	return true, nil
+}
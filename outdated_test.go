@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_findNewerStatus(t *testing.T) {
+	triggeredAt := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		statuses    []commitStatus
+		buildNumber int
+		want        bool
+	}{
+		{"no statuses", nil, 42, false},
+		{"older build number", []commitStatus{{Description: "Success (build #41)"}}, 42, false},
+		{"same build number", []commitStatus{{Description: "Running (build #42)"}}, 42, false},
+		{"newer build number", []commitStatus{{Description: "Success (build #43)"}}, 42, true},
+		{"issue reference", []commitStatus{{Description: "Fixes #123"}}, 42, false},
+		{"issue reference before the build number", []commitStatus{{Description: "Fixes #123 (build #41)"}}, 42, false},
+		{"build number not at the end", []commitStatus{{Description: "Success (build #43) retried"}}, 42, false},
+		{"older build reported late", []commitStatus{{Description: "Success (build #41)", TargetURL: "https://app.bitrise.io/build/a", CreatedAt: triggeredAt.Add(10 * time.Minute)}}, 42, false},
+		{"no build number in status", []commitStatus{{Description: "Success", TargetURL: "https://app.bitrise.io/build/c", CreatedAt: triggeredAt.Add(time.Minute)}}, 42, false},
+		{"no build number of current build", []commitStatus{{Description: "Success (build #43)"}}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := findNewerStatus(tt.statuses, tt.buildNumber); got != tt.want {
+				t.Errorf("findNewerStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_withBuildNumber(t *testing.T) {
+	tests := []struct {
+		name        string
+		desc        string
+		buildNumber int
+		want        string
+	}{
+		{"appended", "Success", 42, "Success (build #42)"},
+		{"issue reference", "Fixes #123", 42, "Fixes #123 (build #42)"},
+		{"already tagged", "Success (build #42)", 42, "Success (build #42)"},
+		{"no build number", "Success", 0, "Success"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := withBuildNumber(tt.desc, tt.buildNumber); got != tt.want {
+				t.Errorf("withBuildNumber() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/retry"
+)
+
+const defaultPackageMaxSizeMB = 500
+
+var packageNameInvalidChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// packageFile is an uploaded generic package file as returned by the GitLab API.
+// see also: https://docs.gitlab.com/ee/user/packages/generic_packages/#publish-a-package-file
+type packageFile struct {
+	ID         int    `json:"id"`
+	PackageID  int    `json:"package_id"`
+	FileName   string `json:"file_name"`
+	Size       int64  `json:"size"`
+	FileSHA256 string `json:"file_sha256"`
+}
+
+// publishedPackage is a file published to the generic package registry.
+type publishedPackage struct {
+	Path      string
+	URL       string
+	PackageID int
+}
+
+// getPackageName returns the configured package name, or the sanitized branch name if it is not set.
+func getPackageName(cfg config) string {
+	name := cfg.PackageName
+	if name == "" {
+		name = strings.TrimSpace(cfg.GitRef)
+	}
+	name = strings.Trim(packageNameInvalidChars.ReplaceAllString(name, "-"), "-.")
+	if name == "" {
+		return "bitrise"
+	}
+	return name
+}
+
+// getPackageVersion returns the configured package version, or the build number if it is not set.
+func getPackageVersion(cfg config) string {
+	if cfg.PackageVersion != "" {
+		return cfg.PackageVersion
+	}
+	return fmt.Sprintf("%d", cfg.BuildNumber)
+}
+
+func fileSHA256(pth string) (string, error) {
+	f, err := os.Open(pth)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Warnf("Failed to close file (%s), error: %s", pth, err)
+		}
+	}()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// packageFilePath returns the API path of the package file, which is also its download URL.
+func packageFilePath(cfg config, name, version, fileName string) string {
+	return fmt.Sprintf("projects/%s/packages/generic/%s/%s/%s", projectPath(cfg), url.PathEscape(name), url.PathEscape(version), url.PathEscape(fileName))
+}
+
+// uploadPackageFile uploads the file to the generic package registry and verifies its checksum.
+func uploadPackageFile(cfg config, name, version, pth string) (packageFile, error) {
+	checksum, err := fileSHA256(pth)
+	if err != nil {
+		return packageFile{}, err
+	}
+
+	var uploaded packageFile
+	path := packageFilePath(cfg, name, version, filepath.Base(pth))
+	if err := retry.Times(3).Wait(5 * time.Second).Try(func(attempt uint) error {
+		if attempt > 0 {
+			log.Warnf("%d attempt failed", attempt)
+		}
+
+		f, err := os.Open(pth)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := f.Close(); err != nil {
+				log.Warnf("Failed to close file (%s), error: %s", pth, err)
+			}
+		}()
+
+		return apiRequest(cfg, http.MethodPut, path, url.Values{"select": {"package_file"}}, f, "application/octet-stream", &uploaded)
+	}); err != nil {
+		return packageFile{}, err
+	}
+
+	if uploaded.FileSHA256 != "" && uploaded.FileSHA256 != checksum {
+		return packageFile{}, fmt.Errorf("checksum mismatch, local: %s uploaded: %s", checksum, uploaded.FileSHA256)
+	}
+	return uploaded, nil
+}
+
+// publishPackages uploads the files matching the package paths to the generic package registry
+// and exports the URLs of the package files.
+func publishPackages(cfg config) ([]publishedPackage, error) {
+	maxSizeMB := cfg.PackageMaxSizeMB
+	if maxSizeMB <= 0 {
+		maxSizeMB = defaultPackageMaxSizeMB
+	}
+	maxSize := int64(maxSizeMB) * 1024 * 1024
+
+	name, version := getPackageName(cfg), getPackageVersion(cfg)
+	log.Infof("Publishing package %s %s", name, version)
+
+	var published []publishedPackage
+	for _, pattern := range cfg.PackagePaths {
+		if pattern = strings.TrimSpace(pattern); pattern == "" {
+			continue
+		}
+		pths, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid package path (%s): %s", pattern, err)
+		}
+
+		for _, pth := range pths {
+			info, err := os.Stat(pth)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			if info.Size() > maxSize {
+				log.Warnf("Skipping %s, its size (%d bytes) exceeds the limit (%d bytes)", pth, info.Size(), maxSize)
+				continue
+			}
+
+			uploaded, err := uploadPackageFile(cfg, name, version, pth)
+			if err != nil {
+				return published, fmt.Errorf("failed to upload %s: %s", pth, err)
+			}
+			u := fmt.Sprintf("%s/%s", strings.TrimSuffix(cfg.APIURL, "/"), packageFilePath(cfg, name, version, filepath.Base(pth)))
+			log.Donef("Uploaded %s: %s", pth, u)
+			published = append(published, publishedPackage{Path: pth, URL: u, PackageID: uploaded.PackageID})
+		}
+	}
+
+	var urls []string
+	for _, p := range published {
+		urls = append(urls, p.URL)
+	}
+	if err := exportEnv("GITLAB_PACKAGE_FILE_URLS", strings.Join(urls, "\n")); err != nil {
+		log.Warnf("%s", err)
+	}
+	return published, nil
+}
+
+// packagesSection renders the links of the published package files for the merge request summary comment.
+func packagesSection(published []publishedPackage) string {
+	var b strings.Builder
+	b.WriteString("**Packages**\n\n")
+	for _, p := range published {
+		fmt.Fprintf(&b, "- [%s](%s)\n", filepath.Base(p.Path), p.URL)
+	}
+	return b.String()
+}
+
+// packageWebURL returns the URL of the package's page in the project's package registry.
+func packageWebURL(cfg config, packageID int) (string, error) {
+	p, err := getProjectInfo(cfg)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/-/packages/%d", p.WebURL, packageID), nil
+}
@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func Test_getPackageName(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config
+		want string
+	}{
+		{"configured", config{PackageName: "my-app", GitRef: "main"}, "my-app"},
+		{"branch", config{GitRef: "main"}, "main"},
+		{"branch with slash", config{GitRef: "feature/login screen"}, "feature-login-screen"},
+		{"no branch", config{}, "bitrise"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getPackageName(tt.cfg); got != tt.want {
+				t.Errorf("getPackageName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// errPollTimeout is returned by poll if the condition is not met within the timeout.
+var errPollTimeout = errors.New("timed out")
+
+// parseDuration parses a duration input, returning def if the input is empty.
+func parseDuration(value string, def time.Duration) (time.Duration, error) {
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration (%s): %s", value, err)
+	}
+	return d, nil
+}
+
+// poll calls check until it reports done, returns an error or the timeout elapses.
+// The wait between the checks starts at interval and is doubled after every check, up to maxInterval.
+func poll(timeout, interval, maxInterval time.Duration, check func() (bool, error)) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		done, err := check()
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return errPollTimeout
+		}
+
+		wait := interval
+		if wait > remaining {
+			wait = remaining
+		}
+		log.Printf("Checking again in %s", wait.Round(time.Second))
+		time.Sleep(wait)
+
+		if interval *= 2; interval > maxInterval {
+			interval = maxInterval
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+const defaultReleaseNotesTemplate = "Built by Bitrise: [build #{{.BuildNumber}}]({{.BuildURL}})"
+
+// release is a GitLab release as returned by the GitLab API.
+// see also: https://docs.gitlab.com/ee/api/releases/
+type release struct {
+	TagName     string `json:"tag_name"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// releaseLink is an asset link of a release.
+// see also: https://docs.gitlab.com/ee/api/releases/links.html
+type releaseLink struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	LinkType string `json:"link_type"`
+}
+
+// releaseNotesData is available in the release notes template.
+type releaseNotesData struct {
+	Tag         string
+	BuildNumber int
+	BuildURL    string
+	CommitHash  string
+}
+
+func jsonRequest(cfg config, method, path string, body, v interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return apiRequest(cfg, method, path, nil, bytes.NewReader(b), "application/json", v)
+}
+
+// releaseNotes returns the content of the changelog file if set, otherwise renders the release notes template.
+func releaseNotes(cfg config, tag string) (string, error) {
+	if cfg.ReleaseNotesPath != "" {
+		b, err := ioutil.ReadFile(cfg.ReleaseNotesPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read release notes: %s", err)
+		}
+		return string(b), nil
+	}
+
+	text := cfg.ReleaseNotesTemplate
+	if text == "" {
+		text = defaultReleaseNotesTemplate
+	}
+	tmpl, err := template.New("release_notes").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid release notes template: %s", err)
+	}
+
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, releaseNotesData{Tag: tag, BuildNumber: cfg.BuildNumber, BuildURL: cfg.TargetURL, CommitHash: cfg.CommitHash}); err != nil {
+		return "", fmt.Errorf("failed to render release notes: %s", err)
+	}
+	return b.String(), nil
+}
+
+// parseReleaseLinks parses the `name=url` release link inputs.
+func parseReleaseLinks(values []string) ([]releaseLink, error) {
+	var links []releaseLink
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		idx := strings.Index(v, "=")
+		if idx <= 0 {
+			return nil, fmt.Errorf("invalid release link, expected name=url: %s", v)
+		}
+
+		link := releaseLink{Name: strings.TrimSpace(v[:idx]), URL: strings.TrimSpace(v[idx+1:]), LinkType: "other"}
+		if strings.Contains(link.URL, "/packages/generic/") {
+			link.LinkType = "package"
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+func isNotFound(err error) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// upsertRelease creates the release of the tag, or updates it if it already exists.
+func upsertRelease(cfg config, tag, notes string) error {
+	name := cfg.ReleaseName
+	if name == "" {
+		name = tag
+	}
+
+	path := fmt.Sprintf("projects/%s/releases/%s", projectPath(cfg), url.PathEscape(tag))
+	var existing release
+	err := apiRequest(cfg, http.MethodGet, path, nil, nil, "", &existing)
+	switch {
+	case isNotFound(err):
+		body := map[string]string{"tag_name": tag, "name": name, "description": notes}
+		if err := jsonRequest(cfg, http.MethodPost, fmt.Sprintf("projects/%s/releases", projectPath(cfg)), body, nil); err != nil {
+			return fmt.Errorf("failed to create release: %s", err)
+		}
+		log.Donef("Created release %s", tag)
+	case err != nil:
+		return fmt.Errorf("failed to get release: %s", err)
+	default:
+		body := map[string]string{"name": name, "description": notes}
+		if err := jsonRequest(cfg, http.MethodPut, path, body, nil); err != nil {
+			return fmt.Errorf("failed to update release: %s", err)
+		}
+		log.Donef("Updated release %s", tag)
+	}
+	return nil
+}
+
+// syncReleaseLinks adds the asset links to the release, links with the same name are updated in place,
+// so rebuilds do not duplicate them.
+func syncReleaseLinks(cfg config, tag string, links []releaseLink) error {
+	path := fmt.Sprintf("projects/%s/releases/%s/assets/links", projectPath(cfg), url.PathEscape(tag))
+	var existing []releaseLink
+	if err := apiRequest(cfg, http.MethodGet, path, nil, nil, "", &existing); err != nil {
+		return fmt.Errorf("failed to list release links: %s", err)
+	}
+	byName := map[string]releaseLink{}
+	for _, l := range existing {
+		byName[l.Name] = l
+	}
+
+	for _, l := range links {
+		body := map[string]string{"name": l.Name, "url": l.URL, "link_type": l.LinkType}
+		e, ok := byName[l.Name]
+		switch {
+		case !ok:
+			if err := jsonRequest(cfg, http.MethodPost, path, body, nil); err != nil {
+				return fmt.Errorf("failed to add release link %s: %s", l.Name, err)
+			}
+			log.Printf("Added release link %s: %s", l.Name, l.URL)
+		case e.URL != l.URL || e.LinkType != l.LinkType:
+			if err := jsonRequest(cfg, http.MethodPut, fmt.Sprintf("%s/%d", path, e.ID), body, nil); err != nil {
+				return fmt.Errorf("failed to update release link %s: %s", l.Name, err)
+			}
+			log.Printf("Updated release link %s: %s", l.Name, l.URL)
+		default:
+			log.Printf("Release link %s is up to date", l.Name)
+		}
+	}
+	return nil
+}
+
+// createRelease creates or updates the GitLab release of the tag with its asset links.
+func createRelease(cfg config) error {
+	tag := strings.TrimSpace(cfg.ReleaseTag)
+	if tag == "" {
+		log.Warnf("Not a tag build, no release is created")
+		return nil
+	}
+
+	links, err := parseReleaseLinks(cfg.ReleaseLinks)
+	if err != nil {
+		return err
+	}
+	notes, err := releaseNotes(cfg, tag)
+	if err != nil {
+		return err
+	}
+
+	if err := upsertRelease(cfg, tag, notes); err != nil {
+		return err
+	}
+	if len(links) > 0 {
+		return syncReleaseLinks(cfg, tag, links)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parseReleaseLinks(t *testing.T) {
+	got, err := parseReleaseLinks([]string{
+		"iOS app=https://app.bitrise.io/artifact/1/p/abc",
+		" APK = https://gitlab.com/api/v4/projects/1/packages/generic/main/42/app.apk ",
+		"",
+	})
+	if err != nil {
+		t.Fatalf("parseReleaseLinks() error = %v", err)
+	}
+	want := []releaseLink{
+		{Name: "iOS app", URL: "https://app.bitrise.io/artifact/1/p/abc", LinkType: "other"},
+		{Name: "APK", URL: "https://gitlab.com/api/v4/projects/1/packages/generic/main/42/app.apk", LinkType: "package"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseReleaseLinks() = %v, want %v", got, want)
+	}
+
+	if _, err := parseReleaseLinks([]string{"https://app.bitrise.io"}); err == nil {
+		t.Errorf("parseReleaseLinks() expected error for link without name")
+	}
+}
+
+func Test_releaseNotes(t *testing.T) {
+	cfg := config{BuildNumber: 42, TargetURL: "https://app.bitrise.io/build/abc"}
+	got, err := releaseNotes(cfg, "1.0.0")
+	if err != nil {
+		t.Fatalf("releaseNotes() error = %v", err)
+	}
+	if want := "Built by Bitrise: [build #42](https://app.bitrise.io/build/abc)"; got != want {
+		t.Errorf("releaseNotes() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// buildReport holds what the build reports besides its status.
+type buildReport struct {
+	// sections are added to the merge request summary comment.
+	sections    []string
+	attachments string
+	coverageErr error
+	// testResults are commented separately on the merge request if there is no summary comment.
+	testResults *testResults
+}
+
+// prepareReport computes the coverage and the test results, and uploads the attachments and packages
+// before the status is reported, as they can change the status, its description and target url.
+func prepareReport(cfg config, mr *mergeRequest) (config, buildReport) {
+	var report buildReport
+
+	if len(cfg.CoverageReportPaths) > 0 {
+		coverage, err := computeCoverage(cfg.CoverageReportPaths)
+		if err != nil {
+			log.Warnf("Failed to compute coverage, error: %s", err)
+		} else {
+			log.Printf("Coverage: %.2f%%", coverage)
+			cfg.Coverage = &coverage
+		}
+	}
+
+	if cfg.Mode == "start" {
+		return cfg, report
+	}
+
+	// The gate is applied to the outcome of the build, a running build is not failed by it.
+	if isCoverageCheckEnabled(cfg) && !isUnfinished(getState(cfg.Status)) {
+		cfg, report.coverageErr = checkCoverage(cfg, mr)
+	}
+
+	if cfg.TestResultsSummary {
+		results, err := parseTestResults(cfg.TestResultsDir)
+		if err != nil {
+			log.Warnf("Failed to parse test results, error: %s", err)
+		} else if results.total() == 0 {
+			log.Warnf("No test results found in %s", cfg.TestResultsDir)
+		} else {
+			log.Printf("Test results: %s", results)
+			cfg.Description = fmt.Sprintf("%s, %s", getDescription(cfg.Description, cfg.Status), results)
+			report.sections = append(report.sections, failedTestsSection(results))
+			report.testResults = &results
+		}
+	}
+
+	if len(cfg.AttachmentPaths) > 0 {
+		var err error
+		if report.attachments, err = attachmentsSection(cfg); err != nil {
+			log.Warnf("Failed to upload attachments, error: %s", err)
+		}
+	}
+
+	// Packages are published once per build, by the run reporting the outcome,
+	// as uploading the same name and version again adds duplicate package files.
+	if len(cfg.PackagePaths) > 0 && isUnfinished(getState(cfg.Status)) {
+		log.Printf("Build is not finished yet, packages are published when its outcome is reported")
+	} else if len(cfg.PackagePaths) > 0 {
+		published, err := publishPackages(cfg)
+		if err != nil {
+			log.Warnf("Failed to publish packages, error: %s", err)
+		}
+		if len(published) > 0 {
+			report.sections = append(report.sections, packagesSection(published))
+			if cfg.PackageTargetURL {
+				if u, err := packageWebURL(cfg, published[0].PackageID); err != nil {
+					log.Warnf("Failed to get package URL, error: %s", err)
+				} else {
+					cfg.TargetURL = u
+				}
+			}
+		}
+	}
+
+	return cfg, report
+}
+
+// publishReport adds the report to the merge request, or comments the attachments on the commit
+// if there is no merge request summary comment to embed them in.
+func publishReport(cfg config, mr *mergeRequest, state string, report buildReport) {
+	sections := report.sections
+	if report.attachments != "" {
+		if mr != nil && cfg.SummaryNote {
+			sections = append(sections, report.attachments)
+		} else {
+			sha := statusTargets(cfg, mr)[0].CommitHash
+			if err := createCommitComment(cfg, sha, report.attachments); err != nil {
+				log.Warnf("Failed to comment attachments on %s, error: %s", shortSHA(sha), err)
+			}
+		}
+	}
+
+	if mr != nil && !cfg.SummaryNote && report.testResults != nil {
+		target := cfg
+		target.CommitHash = mr.SHA
+		if err := upsertTestResultsNote(target, mr, *report.testResults); err != nil {
+			log.Warnf("%s", err)
+		}
+	}
+
+	if mr != nil {
+		reportToMergeRequest(cfg, mr, state, sections)
+	}
+}
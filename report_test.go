@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func Test_prepareReport_coverageGate(t *testing.T) {
+	value := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name       string
+		status     string
+		coverage   *float64
+		wantStatus string
+		wantErr    bool
+	}{
+		{name: "below minimum", status: "success", coverage: value(50), wantStatus: "failed", wantErr: true},
+		{name: "above minimum", status: "success", coverage: value(90), wantStatus: "success"},
+		{name: "running build", status: "running", coverage: value(50), wantStatus: "running"},
+		{name: "pending build", status: "pending", coverage: value(50), wantStatus: "pending"},
+		{name: "no coverage", status: "success", wantStatus: "success"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config{Status: tt.status, Coverage: tt.coverage, CoverageMin: value(80)}
+			got, report := prepareReport(cfg, nil)
+			if got.Status != tt.wantStatus {
+				t.Errorf("prepareReport() status = %v, want %v", got.Status, tt.wantStatus)
+			}
+			if (report.coverageErr != nil) != tt.wantErr {
+				t.Errorf("prepareReport() coverage error = %v, wantErr %v", report.coverageErr, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// statusCheck is an external status check of a merge request as returned by the GitLab API.
+// see also: https://docs.gitlab.com/ee/api/status_checks.html
+type statusCheck struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	ExternalURL string `json:"external_url"`
+	Status      string `json:"status"`
+}
+
+// statusCheckStatus maps the commit status state to the external status check response.
+func statusCheckStatus(state string) string {
+	switch state {
+	case "success":
+		return "passed"
+	case "failed", "canceled":
+		return "failed"
+	default:
+		return "pending"
+	}
+}
+
+func listStatusChecks(cfg config, iid int) ([]statusCheck, error) {
+	var checks []statusCheck
+	path := fmt.Sprintf("projects/%s/merge_requests/%d/status_checks", projectPath(cfg), iid)
+	err := apiRequest(cfg, http.MethodGet, path, nil, nil, "", &checks)
+	return checks, err
+}
+
+// findStatusCheck returns the status check with the name, names are compared case-insensitively.
+func findStatusCheck(checks []statusCheck, name string) (statusCheck, bool) {
+	for _, c := range checks {
+		if strings.EqualFold(strings.TrimSpace(c.Name), strings.TrimSpace(name)) {
+			return c, true
+		}
+	}
+	return statusCheck{}, false
+}
+
+// respondToStatusCheck sets the external status check of the merge request's head commit
+// based on the build state.
+func respondToStatusCheck(cfg config, mr *mergeRequest, state string) error {
+	checks, err := listStatusChecks(cfg, mr.IID)
+	if err != nil {
+		return fmt.Errorf("failed to list external status checks: %s", err)
+	}
+	check, ok := findStatusCheck(checks, cfg.ExternalStatusCheck)
+	if !ok {
+		return fmt.Errorf("external status check %s is not configured for the merge request", cfg.ExternalStatusCheck)
+	}
+
+	status := statusCheckStatus(state)
+	form := url.Values{
+		"sha":                      {mr.SHA},
+		"external_status_check_id": {fmt.Sprintf("%d", check.ID)},
+		"status":                   {status},
+	}
+	path := fmt.Sprintf("projects/%s/merge_requests/%d/status_check_responses", projectPath(cfg), mr.IID)
+	if err := apiRequest(cfg, http.MethodPost, path, nil, strings.NewReader(form.Encode()), "application/x-www-form-urlencoded", nil); err != nil {
+		return fmt.Errorf("failed to respond to external status check %s: %s", check.Name, err)
+	}
+	log.Donef("External status check %s: %s", check.Name, status)
+	return nil
+}
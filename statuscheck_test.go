@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func Test_statusCheckStatus(t *testing.T) {
+	tests := []struct {
+		state string
+		want  string
+	}{
+		{state: "success", want: "passed"},
+		{state: "failed", want: "failed"},
+		{state: "canceled", want: "failed"},
+		{state: "running", want: "pending"},
+		{state: "pending", want: "pending"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.state, func(t *testing.T) {
+			if got := statusCheckStatus(tt.state); got != tt.want {
+				t.Errorf("statusCheckStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_findStatusCheck(t *testing.T) {
+	checks := []statusCheck{{ID: 1, Name: "Compliance"}, {ID: 2, Name: "Bitrise CI"}}
+
+	if c, ok := findStatusCheck(checks, "bitrise ci"); !ok || c.ID != 2 {
+		t.Errorf("findStatusCheck() = %v, %v, want check 2", c, ok)
+	}
+	if _, ok := findStatusCheck(checks, "Security"); ok {
+		t.Errorf("findStatusCheck() found a check which is not configured")
+	}
+}
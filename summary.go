@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var stateIcons = map[string]string{
+	"pending":  ":hourglass:",
+	"running":  ":arrows_counterclockwise:",
+	"success":  ":white_check_mark:",
+	"failed":   ":x:",
+	"canceled": ":no_entry_sign:",
+}
+
+// buildTriggeredAt returns the time the current build was triggered at.
+func buildTriggeredAt() (time.Time, bool) {
+	ts, err := strconv.ParseInt(os.Getenv("BITRISE_BUILD_TRIGGER_TIMESTAMP"), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(ts, 0), true
+}
+
+// buildDuration returns the time elapsed since the build was triggered.
+func buildDuration(now time.Time) (time.Duration, bool) {
+	triggeredAt, ok := buildTriggeredAt()
+	if !ok {
+		return 0, false
+	}
+	return now.Sub(triggeredAt).Round(time.Second), true
+}
+
+// summaryBody renders the summary note of the build.
+func summaryBody(cfg config, state string, duration time.Duration, sections []string) string {
+	context := getContext(cfg.Context)
+
+	var b strings.Builder
+	b.WriteString(noteMarker("summary", context) + "\n")
+	fmt.Fprintf(&b, "### %s %s: %s\n\n", stateIcons[state], context, strings.Title(state))
+
+	fmt.Fprintf(&b, "| | |\n|---|---|\n")
+	if cfg.TargetURL != "" {
+		fmt.Fprintf(&b, "| Build | [#%d](%s) |\n", cfg.BuildNumber, cfg.TargetURL)
+	}
+	fmt.Fprintf(&b, "| Commit | %s |\n", cfg.CommitHash)
+	if duration > 0 {
+		fmt.Fprintf(&b, "| Duration | %s |\n", duration)
+	}
+	if cfg.Coverage != nil {
+		fmt.Fprintf(&b, "| Coverage | %.2f%% |\n", *cfg.Coverage)
+	}
+
+	var artifacts []string
+	for _, u := range cfg.ArtifactURLs {
+		if u = strings.TrimSpace(u); u != "" {
+			artifacts = append(artifacts, fmt.Sprintf("- %s", u))
+		}
+	}
+	if len(artifacts) > 0 {
+		fmt.Fprintf(&b, "\n**Artifacts**\n\n%s\n", strings.Join(artifacts, "\n"))
+	}
+
+	for _, section := range sections {
+		fmt.Fprintf(&b, "\n%s\n", section)
+	}
+	return b.String()
+}
+
+// upsertSummaryNote creates or updates the summary note of the build on the merge request,
+// the previous note of the same context is found by its hidden marker and edited in place.
+// If requested, the note is deleted when the build succeeds.
+func upsertSummaryNote(cfg config, mr *mergeRequest, state string, sections []string) error {
+	var body string
+	if state != "success" || !cfg.DeleteSummaryOnSuccess {
+		duration, _ := buildDuration(time.Now())
+		body = summaryBody(cfg, state, duration, sections)
+	}
+	return upsertMarkedNote(cfg, mr, noteMarker("summary", getContext(cfg.Context)), body, true)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_summaryBody(t *testing.T) {
+	coverage := 81.25
+	cfg := config{
+		Context:      "Bitrise",
+		TargetURL:    "https://app.bitrise.io/build/abc",
+		BuildNumber:  42,
+		CommitHash:   "1111111111111111111111111111111111111111",
+		Coverage:     &coverage,
+		ArtifactURLs: []string{"https://app.bitrise.io/artifact/1/p/abc", ""},
+	}
+	body := summaryBody(cfg, "failed", 3*time.Minute, []string{"**Tests**: 1 failed"})
+
+	for _, want := range []string{
+		noteMarker("summary", "Bitrise"),
+		":x: Bitrise: Failed",
+		"| Build | [#42](https://app.bitrise.io/build/abc) |",
+		"| Duration | 3m0s |",
+		"| Coverage | 81.25% |",
+		"- https://app.bitrise.io/artifact/1/p/abc",
+		"**Tests**: 1 failed",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("summaryBody() = %s, want it to contain %s", body, want)
+		}
+	}
+
+	if _, found := findNote([]note{{ID: 1, Body: "LGTM"}, {ID: 2, Body: body}}, noteMarker("summary", "Bitrise")); !found {
+		t.Errorf("findNote() did not find the summary note")
+	}
+	if _, found := findNote([]note{{ID: 2, Body: body}}, noteMarker("summary", "bitrise/ui")); found {
+		t.Errorf("findNote() found the summary note of an other context")
+	}
+}
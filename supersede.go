@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+const supersedeCommitLimit = 20
+
+// shortSHA returns the abbreviated form of a commit hash, as displayed by GitLab.
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
+
+// earlierCommits returns the commits listed after the given one, the commits API lists the newest commit first.
+// If the commit is not listed (for example an older commit is rebuilt, or the branch was force-pushed),
+// the listed commits can be newer than the built one, so none is returned.
+func earlierCommits(commits []commit, sha string) []commit {
+	for i, c := range commits {
+		if c.ID == sha {
+			return commits[i+1:]
+		}
+	}
+	return nil
+}
+
+// cancelSupersededStatuses cancels the pending or running statuses in the configured context
+// on the earlier commits of the same branch, as their builds are superseded by the current one.
+func cancelSupersededStatuses(cfg config) error {
+	ref := strings.TrimSpace(cfg.GitRef)
+	if ref == "" {
+		log.Warnf("No branch set, can not look for superseded commits")
+		return nil
+	}
+
+	commits, err := listCommits(cfg, ref, supersedeCommitLimit)
+	if err != nil {
+		return fmt.Errorf("failed to list commits of %s: %s", ref, err)
+	}
+
+	earlier := earlierCommits(commits, cfg.CommitHash)
+	if len(earlier) == 0 {
+		log.Printf("%s is not among the latest %d commits of %s, no commit is superseded", shortSHA(cfg.CommitHash), supersedeCommitLimit, ref)
+		return nil
+	}
+
+	context := getContext(cfg.Context)
+	desc := fmt.Sprintf("Superseded by %s", shortSHA(cfg.CommitHash))
+	for _, c := range earlier {
+		statuses, err := listStatuses(cfg, c.ID, ref, context)
+		if err != nil {
+			return fmt.Errorf("failed to list statuses of %s: %s", c.ShortID, err)
+		}
+
+		for _, s := range latestStatuses(statuses) {
+			if !isUnfinished(s.Status) {
+				continue
+			}
+			if cfg.DryRun {
+				log.Printf("Would cancel %s status of %s (%s)", s.Status, c.ShortID, s.Name)
+				continue
+			}
+			if err := cancelStatus(cfg, c.ID, s, desc); err != nil {
+				log.Warnf("Failed to cancel superseded status of %s (%s), error: %s", c.ShortID, s.Name, err)
+				continue
+			}
+			log.Donef("Canceled %s status of %s (%s), %s", s.Status, c.ShortID, s.Name, desc)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_earlierCommits(t *testing.T) {
+	commits := []commit{{ID: "c3"}, {ID: "c2"}, {ID: "c1"}}
+	tests := []struct {
+		name string
+		sha  string
+		want []commit
+	}{
+		{name: "found", sha: "c2", want: []commit{{ID: "c1"}}},
+		{name: "found at head", sha: "c3", want: []commit{{ID: "c2"}, {ID: "c1"}}},
+		{name: "found at tail", sha: "c1", want: []commit{}},
+		{name: "not listed", sha: "c0", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := earlierCommits(commits, tt.sha); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("earlierCommits() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
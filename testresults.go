@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+const maxFailedTestsListed = 50
+
+// junitTestCase is a test case of a JUnit XML report.
+type junitTestCase struct {
+	Name      string `xml:"name,attr"`
+	ClassName string `xml:"classname,attr"`
+	Failures  []struct {
+		Message string `xml:"message,attr"`
+		Text    string `xml:",chardata"`
+	} `xml:"failure"`
+	Errors []struct {
+		Message string `xml:"message,attr"`
+		Text    string `xml:",chardata"`
+	} `xml:"error"`
+	Skipped *struct{} `xml:"skipped"`
+}
+
+// junitTestSuite is a test suite of a JUnit XML report, test suites can be nested.
+type junitTestSuite struct {
+	TestCases  []junitTestCase  `xml:"testcase"`
+	TestSuites []junitTestSuite `xml:"testsuite"`
+}
+
+// failedTest is a failed test case with its failure message.
+type failedTest struct {
+	TestRun string
+	Name    string
+	Message string
+}
+
+// testResults are the counts of the test cases in the test result directory, and the failed test cases.
+type testResults struct {
+	Passed  int
+	Failed  int
+	Skipped int
+	Failure []failedTest
+}
+
+func (r testResults) total() int {
+	return r.Passed + r.Failed + r.Skipped
+}
+
+// String returns the counts of the test results, for example "412 passed, 3 failed, 5 skipped".
+func (r testResults) String() string {
+	return fmt.Sprintf("%d passed, %d failed, %d skipped", r.Passed, r.Failed, r.Skipped)
+}
+
+func (r *testResults) addSuite(testRun string, suite junitTestSuite) {
+	for _, tc := range suite.TestCases {
+		name := tc.Name
+		if tc.ClassName != "" {
+			name = tc.ClassName + "." + tc.Name
+		}
+
+		switch {
+		case len(tc.Failures) > 0:
+			r.Failed++
+			r.Failure = append(r.Failure, failedTest{TestRun: testRun, Name: name, Message: firstLine(tc.Failures[0].Message, tc.Failures[0].Text)})
+		case len(tc.Errors) > 0:
+			r.Failed++
+			r.Failure = append(r.Failure, failedTest{TestRun: testRun, Name: name, Message: firstLine(tc.Errors[0].Message, tc.Errors[0].Text)})
+		case tc.Skipped != nil:
+			r.Skipped++
+		default:
+			r.Passed++
+		}
+	}
+	for _, s := range suite.TestSuites {
+		r.addSuite(testRun, s)
+	}
+}
+
+// firstLine returns the first non-empty line of the texts.
+func firstLine(texts ...string) string {
+	for _, text := range texts {
+		for _, line := range strings.Split(text, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				return line
+			}
+		}
+	}
+	return ""
+}
+
+// testRunName reads the name of the test run from the test-info.json of its directory.
+func testRunName(dir string) string {
+	b, err := ioutil.ReadFile(filepath.Join(dir, "test-info.json"))
+	if err != nil {
+		return filepath.Base(dir)
+	}
+	var info struct {
+		TestName string `json:"test-name"`
+	}
+	if err := json.Unmarshal(b, &info); err != nil || info.TestName == "" {
+		return filepath.Base(dir)
+	}
+	return info.TestName
+}
+
+// parseTestResults parses the JUnit XML reports of the Bitrise test result directory,
+// which contains a directory with a test-info.json and the reports for every test run.
+func parseTestResults(dir string) (testResults, error) {
+	var results testResults
+	err := filepath.Walk(dir, func(pth string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || strings.ToLower(filepath.Ext(pth)) != ".xml" {
+			return nil
+		}
+
+		data, err := ioutil.ReadFile(pth)
+		if err != nil {
+			return err
+		}
+
+		// The root element is either <testsuites> or a single <testsuite>, both are decoded as a suite.
+		var suite junitTestSuite
+		if err := xml.Unmarshal(data, &suite); err != nil {
+			log.Warnf("Failed to parse test report (%s), error: %s", pth, err)
+			return nil
+		}
+		results.addSuite(testRunName(filepath.Dir(pth)), suite)
+		return nil
+	})
+	return results, err
+}
+
+// failedTestsSection renders a collapsible table of the failed tests for the merge request summary comment.
+func failedTestsSection(results testResults) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**Tests**: %s\n", results)
+	if len(results.Failure) == 0 {
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "\n<details><summary>%d failed test(s)</summary>\n\n", len(results.Failure))
+	b.WriteString("| Test run | Test | Failure |\n|---|---|---|\n")
+	for i, f := range results.Failure {
+		if i == maxFailedTestsListed {
+			fmt.Fprintf(&b, "| | … and %d more | |\n", len(results.Failure)-maxFailedTestsListed)
+			break
+		}
+		fmt.Fprintf(&b, "| %s | `%s` | %s |\n", escapeTableCell(f.TestRun), escapeTableCell(f.Name), escapeTableCell(f.Message))
+	}
+	b.WriteString("\n</details>\n")
+	return b.String()
+}
+
+// upsertTestResultsNote keeps a test results comment up to date on the merge request,
+// used when the test results are not added to the summary comment.
+// The comment is created when tests fail, and updated by later builds even if every test passes.
+func upsertTestResultsNote(cfg config, mr *mergeRequest, results testResults) error {
+	context := getContext(cfg.Context)
+	body := fmt.Sprintf("%s\n### %s: test results of %s\n\n%s", noteMarker("tests", context), context, cfg.CommitHash, failedTestsSection(results))
+	return upsertMarkedNote(cfg, mr, noteMarker("tests", context), body, len(results.Failure) > 0)
+}
+
+func escapeTableCell(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
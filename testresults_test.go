@@ -0,0 +1,53 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_parseTestResults(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"unit/test-info.json": `{"test-name": "Unit tests"}`,
+		"unit/report.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites><testsuite name="AppTests">
+<testcase classname="AppTests" name="testLogin"/>
+<testcase classname="AppTests" name="testLogout"><failure message="XCTAssertTrue failed">AppTests.swift:42</failure></testcase>
+<testcase classname="AppTests" name="testLater"><skipped/></testcase>
+<testcase classname="AppTests" name="testParsing(a|b)"><failure message="mismatch"/></testcase>
+</testsuite></testsuites>`,
+		"ui/report.xml": `<testsuite name="UITests"><testcase classname="UITests" name="testOnboarding"><error message="">Crashed
+at line 3</error></testcase><testcase classname="UITests" name="testHome"/></testsuite>`,
+	}
+	for name, content := range files {
+		pth := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(pth), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(pth, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := parseTestResults(dir)
+	if err != nil {
+		t.Fatalf("parseTestResults() error = %v", err)
+	}
+	if got, want := results.String(), "2 passed, 3 failed, 1 skipped"; got != want {
+		t.Errorf("parseTestResults() = %v, want %v", got, want)
+	}
+
+	section := failedTestsSection(results)
+	for _, want := range []string{
+		"| ui | `UITests.testOnboarding` | Crashed |",
+		"| Unit tests | `AppTests.testLogout` | XCTAssertTrue failed |",
+		"| Unit tests | `AppTests.testParsing(a\\|b)` | mismatch |",
+	} {
+		if !strings.Contains(section, want) {
+			t.Errorf("failedTestsSection() = %s, want it to contain %s", section, want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+var transitionRegexp = regexp.MustCompile(`Cannot transition status via :(\w+) from :(\w+)`)
+
+// transitionEvents maps GitLab's commit status state machine events to the state they lead to.
+var transitionEvents = map[string]string{
+	"enqueue": "pending",
+	"run":     "running",
+	"success": "success",
+	"drop":    "failed",
+	"cancel":  "canceled",
+}
+
+// transitionError is returned when GitLab rejects changing the commit status from its current state to the requested one.
+type transitionError struct {
+	Event string
+	From  string
+	To    string
+	err   *apiError
+}
+
+func (e *transitionError) Error() string {
+	return fmt.Sprintf("GitLab does not allow changing the status from %s to %s (%s), "+
+		"a status can not be moved back to an earlier state or out of a finished state by the same context, "+
+		"use a different context or a new commit to report a new status", e.From, e.To, e.err.Message)
+}
+
+func (e *transitionError) Unwrap() error {
+	return e.err
+}
+
+// parseTransitionError returns the rejected state transition described by the API error, if any.
+func parseTransitionError(err *apiError) (*transitionError, bool) {
+	if err.StatusCode != http.StatusBadRequest {
+		return nil, false
+	}
+
+	matches := transitionRegexp.FindStringSubmatch(err.Message)
+	if len(matches) != 3 {
+		return nil, false
+	}
+
+	to, ok := transitionEvents[matches[1]]
+	if !ok {
+		to = matches[1]
+	}
+	return &transitionError{Event: matches[1], From: matches[2], To: to, err: err}, true
+}
+
+// checkTransition treats a rejected state transition as success if the commit already has the desired state,
+// and returns a transitionError if the transition is invalid.
+func checkTransition(err error, state string) error {
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+
+	transitionErr, ok := parseTransitionError(apiErr)
+	if !ok {
+		return err
+	}
+
+	if transitionErr.From == state {
+		log.Printf("The commit already has %s status, nothing to update", state)
+		return nil
+	}
+	return transitionErr
+}
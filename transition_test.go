@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func Test_checkTransition(t *testing.T) {
+	transitionErr := func(msg string) error {
+		return &apiError{StatusCode: http.StatusBadRequest, Message: msg}
+	}
+	tests := []struct {
+		name           string
+		err            error
+		state          string
+		wantErr        bool
+		wantTransition bool
+	}{
+		{"no error", nil, "running", false, false},
+		{"other error", errors.New("failed to send the request"), "running", true, false},
+		{"other api error", &apiError{StatusCode: http.StatusNotFound, Message: "404 Project Not Found"}, "running", true, false},
+		{"same state", transitionErr("Cannot transition status via :run from :running"), "running", false, false},
+		{"same state with reason", transitionErr(`Cannot transition status via :success from :success (Reason(s): Status cannot transition via "success")`), "success", false, false},
+		{"invalid transition", transitionErr("Cannot transition status via :run from :success"), "running", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkTransition(tt.err, tt.state)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkTransition() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var te *transitionError
+			if errors.As(err, &te) != tt.wantTransition {
+				t.Errorf("checkTransition() error = %v, wantTransition %v", err, tt.wantTransition)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+const pipelineGateName = "GitLab pipeline"
+
+// gate is a status or pipeline the build waits for.
+type gate struct {
+	Kind  string
+	Name  string
+	State string
+	URL   string
+}
+
+func isGateSucceeded(state string) bool {
+	return state == "success" || state == "skipped"
+}
+
+func isGateFailed(state string) bool {
+	return state == "failed" || state == "canceled"
+}
+
+// collectGates returns the state of every awaited context and the latest GitLab CI pipeline if requested,
+// contexts which did not report yet are included with "not reported" state.
+func collectGates(statuses []commitStatus, pipelines []pipeline, contexts []string, waitForPipeline bool) []gate {
+	latest := map[string]commitStatus{}
+	for _, s := range latestStatuses(statuses) {
+		if l, ok := latest[s.Name]; !ok || s.ID > l.ID {
+			latest[s.Name] = s
+		}
+	}
+
+	var gates []gate
+	for _, context := range contexts {
+		if s, ok := latest[context]; ok {
+			gates = append(gates, gate{Kind: "status", Name: context, State: s.Status, URL: s.TargetURL})
+		} else {
+			gates = append(gates, gate{Kind: "status", Name: context, State: "not reported"})
+		}
+	}
+
+	if waitForPipeline {
+		if p := latestCIPipeline(pipelines); p != nil {
+			gates = append(gates, gate{Kind: "pipeline", Name: fmt.Sprintf("%s #%d", pipelineGateName, p.ID), State: p.Status, URL: p.WebURL})
+		} else {
+			gates = append(gates, gate{Kind: "pipeline", Name: pipelineGateName, State: "not reported"})
+		}
+	}
+	return gates
+}
+
+// latestCIPipeline returns the latest GitLab CI pipeline, the external pipelines holding the commit statuses
+// (including the ones of this build) are skipped.
+func latestCIPipeline(pipelines []pipeline) *pipeline {
+	for i, p := range pipelines {
+		if p.Source != "external" {
+			return &pipelines[i]
+		}
+	}
+	return nil
+}
+
+// evaluateGates returns whether every gate finished and whether any of them failed.
+func evaluateGates(gates []gate) (finished bool, failed bool) {
+	finished = true
+	for _, g := range gates {
+		switch {
+		case isGateSucceeded(g.State):
+		case isGateFailed(g.State):
+			failed = true
+		default:
+			finished = false
+		}
+	}
+	return finished, failed
+}
+
+func gatesTable(gates []gate) string {
+	var b bytes.Buffer
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tNAME\tSTATE\tURL")
+	for _, g := range gates {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", g.Kind, g.Name, g.State, g.URL)
+	}
+	if err := w.Flush(); err != nil {
+		return err.Error()
+	}
+	return b.String()
+}
+
+// waitForStatuses waits until the given statuses and the GitLab pipeline of the commit finish,
+// and fails as soon as any of them fails.
+func waitForStatuses(cfg config) error {
+	if len(cfg.WaitContexts) == 0 && !cfg.WaitForPipeline {
+		return fmt.Errorf("wait_for_statuses mode requires contexts to wait for or waiting for the pipeline")
+	}
+	timeout, err := parseDuration(cfg.WaitTimeout, defaultWaitTimeout)
+	if err != nil {
+		return err
+	}
+	interval, err := parseDuration(cfg.PollInterval, defaultPollInterval)
+	if err != nil {
+		return err
+	}
+	ref := strings.TrimSpace(cfg.GitRef)
+
+	var gates []gate
+	var failed bool
+	err = poll(timeout, interval, defaultMaxPollInterval, func() (bool, error) {
+		statuses, err := listStatuses(cfg, cfg.CommitHash, ref, "")
+		if err != nil {
+			return false, fmt.Errorf("failed to list statuses: %s", err)
+		}
+
+		var pipelines []pipeline
+		if cfg.WaitForPipeline {
+			if pipelines, err = listPipelines(cfg, cfg.CommitHash, ref); err != nil {
+				return false, fmt.Errorf("failed to list pipelines: %s", err)
+			}
+		}
+
+		gates = collectGates(statuses, pipelines, cfg.WaitContexts, cfg.WaitForPipeline)
+		var finished bool
+		finished, failed = evaluateGates(gates)
+		log.Printf("%s: %d of %d finished", time.Now().Format("15:04:05"), countFinished(gates), len(gates))
+		return finished || failed, nil
+	})
+
+	if err == errPollTimeout {
+		log.Printf("%s", gatesTable(gates))
+		return fmt.Errorf("statuses did not finish in %s", timeout)
+	} else if err != nil {
+		return err
+	}
+
+	log.Printf("%s", gatesTable(gates))
+	if failed {
+		return fmt.Errorf("some of the awaited statuses did not succeed")
+	}
+	log.Donef("All awaited statuses succeeded")
+	return nil
+}
+
+func countFinished(gates []gate) int {
+	var n int
+	for _, g := range gates {
+		if isGateSucceeded(g.State) || isGateFailed(g.State) {
+			n++
+		}
+	}
+	return n
+}
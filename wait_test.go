@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func Test_evaluateGates(t *testing.T) {
+	statuses := []commitStatus{
+		{ID: 1, Name: "gitlab-ci/test", Ref: "main", Status: "running"},
+		{ID: 2, Name: "gitlab-ci/test", Ref: "main", Status: "success"},
+		{ID: 3, Name: "security-scan", Ref: "main", Status: "failed"},
+		{ID: 4, Name: "lint", Ref: "main", Status: "pending"},
+	}
+	tests := []struct {
+		name         string
+		contexts     []string
+		pipelines    []pipeline
+		waitPipeline bool
+		wantFinished bool
+		wantFailed   bool
+	}{
+		{"succeeded", []string{"gitlab-ci/test"}, nil, false, true, false},
+		{"failed", []string{"gitlab-ci/test", "security-scan"}, nil, false, true, true},
+		{"pending", []string{"gitlab-ci/test", "lint"}, nil, false, false, false},
+		{"not reported", []string{"missing"}, nil, false, false, false},
+		{"pipeline running", []string{"gitlab-ci/test"}, []pipeline{{ID: 2, Status: "running"}, {ID: 1, Status: "success"}}, true, false, false},
+		{"pipeline succeeded", []string{"gitlab-ci/test"}, []pipeline{{ID: 2, Status: "success"}}, true, true, false},
+		{"no pipeline", nil, nil, true, false, false},
+		{"external pipeline skipped", []string{"gitlab-ci/test"}, []pipeline{{ID: 3, Status: "running", Source: "external"}, {ID: 2, Status: "success", Source: "push"}}, true, true, false},
+		{"only external pipeline", nil, []pipeline{{ID: 3, Status: "success", Source: "external"}}, true, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gates := collectGates(statuses, tt.pipelines, tt.contexts, tt.waitPipeline)
+			finished, failed := evaluateGates(gates)
+			if finished != tt.wantFinished || failed != tt.wantFailed {
+				t.Errorf("evaluateGates() = %v, %v, want %v, %v", finished, failed, tt.wantFinished, tt.wantFailed)
+			}
+		})
+	}
+}